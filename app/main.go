@@ -1,32 +1,78 @@
 package main
 
 import (
+    "context"
+   	"flag"
    	"fmt"
+    "log"
+    "os"
+    "os/signal"
+    "syscall"
+    ytapi "github.com/Arthur-Scaratti/yt-api"
     "github.com/Arthur-Scaratti/yt-api/config"
-	"github.com/Arthur-Scaratti/yt-api/handlers"
-    utils "github.com/Arthur-Scaratti/yt-api/utils"
-	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
 )
 
+const usage = `Uso:
+  ytapi [--config arquivo.yaml]               inicia o servidor
+  ytapi config print [--config arquivo.yaml]  mostra a configuração efetiva
+
+Sem --config é usado o arquivo em YTAPI_CONFIG, se definido. Variáveis de
+ambiente têm precedência sobre o arquivo.
+`
+
 func main() {
-    cfg := config.Load()
+    args := os.Args[1:]
+    printConfig := len(args) >= 2 && args[0] == "config" && args[1] == "print"
+    if printConfig {
+        args = args[2:]
+    }
     
-    if cfg.GinMode == "debug" {
-        gin.SetMode(gin.DebugMode)
-    } else {
-        gin.SetMode(gin.ReleaseMode)
+    flags := flag.NewFlagSet("ytapi", flag.ExitOnError)
+    flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+    configPath := flags.String("config", "", "arquivo de configuração YAML")
+    flags.Parse(args)
+    if flags.NArg() > 0 {
+        flags.Usage()
+        os.Exit(2)
     }
     
-    r := gin.Default()
+    cfg := config.LoadFile(*configPath)
+    if printConfig {
+        os.Exit(runConfigPrint(cfg))
+    }
     
-    // Configurar rotas usando variáveis de ambiente
-    r.GET(cfg.DownloadHandler, handlers.DownloadHandler)
-    r.GET(cfg.PlaylistHandler, handlers.PlaylistHandler)
-    r.GET(cfg.WebSocketHandler, handlers.WebSocketHandler)
+    // Não sobe com configuração inválida
+    server, err := ytapi.New(cfg)
+    if err != nil {
+        log.Fatal(err)
+    }
+    server.ReloadOnSignal()
     
-    utils.StartAutoCleanup()
-    utils.RunSimpleCleanup()
-    // Usar host e porta das variáveis de ambiente
-    address := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-    r.Run(address)
+    // SIGINT/SIGTERM encerram graciosamente: os downloads em andamento
+    // têm até SHUTDOWN_TIMEOUT para terminar
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    if err := server.ListenAndServe(ctx); err != nil {
+        log.Fatal(err)
+    }
+}
+
+// runConfigPrint escreve a configuração efetiva (padrões, arquivo e
+// ambiente) em YAML, com os tokens mascarados. Problemas de validação vão
+// para o stderr e mudam o código de saída
+func runConfigPrint(cfg *config.Config) int {
+    encoder := yaml.NewEncoder(os.Stdout)
+    encoder.SetIndent(2)
+    if err := encoder.Encode(cfg.Redacted()); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    encoder.Close()
+    
+    if err := cfg.Validate(); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 1
+    }
+    return 0
 }
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Subprotocolo usado para enviar o token no upgrade do WebSocket, já que o
+// navegador não permite headers: new WebSocket(url, ["bearer", token])
+const BearerProtocol = "bearer"
+
+// Limits são as cotas de uma chave; zero é ilimitado
+type Limits struct {
+	// Jobs na fila ou rodando ao mesmo tempo
+	MaxConcurrentJobs int `json:"maxConcurrentJobs"`
+	// Downloads pedidos por dia
+	MaxDailyDownloads int `json:"maxDailyDownloads"`
+	// Bytes enviados nas respostas, somados desde a criação da chave
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// Key é uma chave de acesso; Name identifica o dono dos jobs criados com ela
+type Key struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Limits
+}
+
+// Keys é o conjunto de chaves aceitas. Sem chaves configuradas a
+// autenticação fica desligada
+type Keys struct {
+	enabled bool
+	keys    []*Key
+}
+
+// NewKeys cria o conjunto a partir de entradas "nome:token" e do arquivo de
+// chaves (JSON), se informado. Limites zerados de cada chave recebem os
+// padrões. Entradas inválidas e erros do arquivo não desligam a
+// autenticação: sem chaves válidas nenhuma requisição passa. Entradas
+// ignoradas são avisadas em logger (nil usa o logger padrão)
+func NewKeys(entries []string, file string, defaults Limits, logger *log.Logger) (*Keys, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	k := &Keys{enabled: len(entries) > 0 || file != ""}
+	for _, entry := range entries {
+		name, token, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || token == "" {
+			logger.Printf("Chave de API inválida ignorada (esperado nome:token)")
+			continue
+		}
+		k.add(&Key{Name: name, Token: token}, defaults)
+	}
+
+	if file == "" {
+		return k, nil
+	}
+	fileKeys, err := loadKeyFile(file)
+	if err != nil {
+		return k, err
+	}
+	for _, key := range fileKeys {
+		if key.Name == "" || key.Token == "" {
+			logger.Printf("Chave de API inválida ignorada em %s (name e token são obrigatórios)", file)
+			continue
+		}
+		k.add(key, defaults)
+	}
+	return k, nil
+}
+
+func (k *Keys) add(key *Key, defaults Limits) {
+	if key.MaxConcurrentJobs == 0 {
+		key.MaxConcurrentJobs = defaults.MaxConcurrentJobs
+	}
+	if key.MaxDailyDownloads == 0 {
+		key.MaxDailyDownloads = defaults.MaxDailyDownloads
+	}
+	if key.MaxBytes == 0 {
+		key.MaxBytes = defaults.MaxBytes
+	}
+	k.keys = append(k.keys, key)
+}
+
+// loadKeyFile lê uma lista JSON de chaves:
+// [{"name": "alice", "token": "...", "maxDailyDownloads": 100}]
+func loadKeyFile(path string) ([]*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lendo arquivo de chaves: %w", err)
+	}
+	var keys []*Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("arquivo de chaves %s inválido: %w", path, err)
+	}
+	return keys, nil
+}
+
+// Enabled indica se a autenticação está ligada
+func (k *Keys) Enabled() bool {
+	return k != nil && k.enabled
+}
+
+// Lookup busca a chave pelo token
+func (k *Keys) Lookup(token string) (*Key, bool) {
+	if k == nil || token == "" {
+		return nil, false
+	}
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Token), []byte(token)) == 1 {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// TokenFromRequest extrai o token do header Authorization (Bearer), do
+// subprotocolo "bearer" do WebSocket ou do parâmetro token da query
+func TokenFromRequest(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	protocols := websocketProtocols(r)
+	for i, protocol := range protocols {
+		if protocol == BearerProtocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+
+	return r.URL.Query().Get("token")
+}
+
+// RedactToken troca o valor do parâmetro token da query de path (ou de uma
+// URL) por "***", mantendo o resto como veio, para que o token não vá para
+// os logs de acesso
+func RedactToken(path string) string {
+	base, query, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && name == "token" {
+			params[i] = key + "=***"
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	return protocols
+}
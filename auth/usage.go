@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// UsageFileName é o arquivo, dentro de DOWNLOAD_DIR, com o consumo das chaves
+const UsageFileName = ".usage.json"
+
+var (
+	ErrConcurrentLimit = errors.New("limite de jobs simultâneos da chave atingido")
+	ErrDailyLimit      = errors.New("limite diário de downloads da chave atingido")
+	ErrByteLimit       = errors.New("limite de bytes da chave atingido")
+)
+
+// KeyUsage é o consumo acumulado de uma chave
+type KeyUsage struct {
+	// Dia (AAAA-MM-DD) a que Downloads se refere
+	Day       string `json:"day"`
+	Downloads int    `json:"downloads"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// Usage contabiliza o consumo por chave em memória e grava em disco
+// periodicamente (StartFlush) e no Close, para que as cotas sobrevivam a um
+// restart sem uma gravação a cada resposta
+type Usage struct {
+	mu     sync.Mutex
+	path   string
+	usage  map[string]*KeyUsage
+	logger *log.Logger
+	// Há consumo ainda não gravado
+	dirty bool
+	// Depois do Close cada alteração é gravada na hora
+	closed bool
+	stop   chan struct{}
+
+	// Serializa as gravações, feitas fora de mu
+	writeMu sync.Mutex
+}
+
+// OpenUsage carrega o consumo gravado em path. Sem path o consumo fica só
+// em memória. Erros de gravação vão para logger (nil usa o logger padrão)
+func OpenUsage(path string, logger *log.Logger) (*Usage, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	u := &Usage{path: path, usage: make(map[string]*KeyUsage), logger: logger}
+	if path == "" {
+		return u, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	if err := json.Unmarshal(data, &u.usage); err != nil {
+		return u, err
+	}
+	return u, nil
+}
+
+// ReserveDownload confere a cota diária e já conta o download, de forma
+// atômica, para que requisições simultâneas não passem do limite. Se o
+// download não chegar a ser aceito a vaga volta com ReleaseDownload
+func (u *Usage) ReserveDownload(key *Key) error {
+	u.mu.Lock()
+	usage := u.get(key.Name)
+	if key.MaxDailyDownloads > 0 && usage.Downloads >= key.MaxDailyDownloads {
+		u.mu.Unlock()
+		return ErrDailyLimit
+	}
+	usage.Downloads++
+	u.dirty = true
+	closed := u.closed
+	u.mu.Unlock()
+
+	if closed {
+		u.save()
+	}
+	return nil
+}
+
+// ReleaseDownload devolve um download reservado com ReserveDownload. Depois
+// da virada do dia não há o que devolver
+func (u *Usage) ReleaseDownload(name string) {
+	u.mu.Lock()
+	usage := u.get(name)
+	if usage.Downloads == 0 {
+		u.mu.Unlock()
+		return
+	}
+	usage.Downloads--
+	u.dirty = true
+	closed := u.closed
+	u.mu.Unlock()
+
+	if closed {
+		u.save()
+	}
+}
+
+// CheckBytes verifica a cota de bytes da chave
+func (u *Usage) CheckBytes(key *Key) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if key.MaxBytes > 0 && u.get(key.Name).Bytes >= key.MaxBytes {
+		return ErrByteLimit
+	}
+	return nil
+}
+
+// AddDownload conta um download no dia atual
+func (u *Usage) AddDownload(name string) {
+	u.mu.Lock()
+	u.get(name).Downloads++
+	u.dirty = true
+	closed := u.closed
+	u.mu.Unlock()
+
+	if closed {
+		u.save()
+	}
+}
+
+// AddBytes soma bytes enviados à chave
+func (u *Usage) AddBytes(name string, n int64) {
+	if n <= 0 {
+		return
+	}
+	u.mu.Lock()
+	u.get(name).Bytes += n
+	u.dirty = true
+	closed := u.closed
+	u.mu.Unlock()
+
+	if closed {
+		u.save()
+	}
+}
+
+// StartFlush grava o consumo pendente a cada interval até o Close
+func (u *Usage) StartFlush(interval time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.stop != nil || u.closed || u.path == "" {
+		return
+	}
+
+	stop := make(chan struct{})
+	u.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				u.save()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close para a gravação periódica e grava o consumo pendente. O consumo
+// contado depois (respostas ainda em andamento) é gravado na hora
+func (u *Usage) Close() error {
+	u.mu.Lock()
+	if u.stop != nil {
+		close(u.stop)
+		u.stop = nil
+	}
+	u.closed = true
+	u.mu.Unlock()
+	return u.Flush()
+}
+
+// Flush grava o consumo pendente, se houver
+func (u *Usage) Flush() error {
+	if u.path == "" {
+		return nil
+	}
+	u.writeMu.Lock()
+	defer u.writeMu.Unlock()
+
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(u.usage)
+	u.dirty = false
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := u.write(data); err != nil {
+		// Tenta de novo na próxima gravação
+		u.mu.Lock()
+		u.dirty = true
+		u.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Get retorna uma cópia do consumo da chave
+func (u *Usage) Get(name string) KeyUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return *u.get(name)
+}
+
+// get busca o consumo da chave, zerando a contagem diária na virada do dia
+func (u *Usage) get(name string) *KeyUsage {
+	today := time.Now().Format(time.DateOnly)
+	usage, ok := u.usage[name]
+	if !ok {
+		usage = &KeyUsage{Day: today}
+		u.usage[name] = usage
+	}
+	if usage.Day != today {
+		usage.Day = today
+		usage.Downloads = 0
+	}
+	return usage
+}
+
+func (u *Usage) save() {
+	if err := u.Flush(); err != nil {
+		u.logger.Printf("Erro ao gravar consumo das chaves: %v", err)
+	}
+}
+
+func (u *Usage) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return err
+	}
+
+	// Grava em arquivo temporário e renomeia para não deixar JSON pela metade
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.path)
+}
+
+// NextDay retorna quanto falta para a contagem diária zerar
+func NextDay() time.Duration {
+	now := time.Now()
+	year, month, day := now.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Sub(now)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestUsageFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), UsageFileName)
+	logger := log.New(io.Discard, "", 0)
+
+	u, err := OpenUsage(path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.AddDownload("alice")
+	u.AddBytes("alice", 100)
+
+	// O consumo fica em memória até o Flush
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("consumo gravado antes do Flush: %v", err)
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Depois do Close cada alteração é gravada na hora
+	u.AddBytes("alice", 50)
+
+	reopened, err := OpenUsage(path, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.Get("alice")
+	if got.Downloads != 1 || got.Bytes != 150 {
+		t.Fatalf("consumo relido = %+v, esperado 1 download e 150 bytes", got)
+	}
+}
+
+func TestUsageReserveDownload(t *testing.T) {
+	u, err := OpenUsage("", log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &Key{Name: "alice", Limits: Limits{MaxDailyDownloads: 3}}
+
+	// Reservas simultâneas não passam da cota
+	const requests = 20
+	var mu sync.Mutex
+	reserved := 0
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if u.ReserveDownload(key) == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 3 || u.Get("alice").Downloads != 3 {
+		t.Fatalf("%d reservas aceitas, %d contadas; esperado 3", reserved, u.Get("alice").Downloads)
+	}
+	if err := u.ReserveDownload(key); !errors.Is(err, ErrDailyLimit) {
+		t.Fatalf("reserva acima da cota: erro = %v, esperado %v", err, ErrDailyLimit)
+	}
+
+	// A vaga devolvida pode ser reservada de novo
+	u.ReleaseDownload("alice")
+	if err := u.ReserveDownload(key); err != nil {
+		t.Fatalf("reserva depois do ReleaseDownload: %v", err)
+	}
+
+	// Devolver sem reserva não deixa a contagem negativa
+	u.ReleaseDownload("bob")
+	if got := u.Get("bob").Downloads; got != 0 {
+		t.Fatalf("downloads de bob = %d, esperado 0", got)
+	}
+}
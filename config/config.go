@@ -1,39 +1,102 @@
 package config
 
 import (
+    "fmt"
     "log"
     "os"
     "strconv"
+    "strings"
+    "time"
     
     "github.com/joho/godotenv"
 )
 
 type Config struct {
+    // Erros de conversão das variáveis, reportados por Validate
+    loadErrors []string
+    // Arquivo de onde a configuração foi lida, relido no reload
+    source string
+    // Ajustes feitos em código, reaplicados no reload
+    overrides []func(*Config)
+    // Como LoadFile montou a configuração; nil quando ela foi criada em
+    // código (Default), que não tem de onde ser relida
+    loaded *Config
+    
     // Server
     GinMode    string
     Port       string
     Host       string
+    // Tempo que o encerramento espera os downloads em andamento antes de
+    // interromper o yt-dlp
+    ShutdownTimeout time.Duration
     
     // Handlers
     DownloadHandler  string
     PlaylistHandler  string
     WebSocketHandler string
+    JobsHandler      string
+    EventsHandler    string
+    FetchHandler     string
+    AdminHandler     string
+    
+    // Segurança
+    APIKeys          []string
+    APIKeysFile      string
+    WSAllowedOrigins []string
+    // Proxies (IPs ou CIDRs) cujos X-Forwarded-For/X-Real-IP são aceitos
+    // como IP do cliente; vazio não confia em nenhum
+    TrustedProxies []string
+    // Nomes das chaves de API que podem usar as rotas de administração
+    AdminKeys []string
+    
+    // Cotas padrão por chave (0 = ilimitado)
+    APIKeyMaxJobs  int
+    APIKeyMaxDaily int
+    APIKeyMaxBytes int64
+    
+    // Política de URLs enviadas ao yt-dlp
+    URLAllowedSchemes    []string
+    URLAllowedHosts      []string
+    URLDeniedHosts       []string
+    URLAllowPrivate      bool
+    URLAllowedExtractors []string
+    // Libera o extractor genérico do yt-dlp, que baixa de qualquer página,
+    // quando URLAllowedExtractors está vazio
+    URLAllowGeneric bool
+    
+    // Rate limit por cliente, em requisições por minuto (0 = sem limite)
+    RateLimitDownload      int
+    RateLimitDownloadBurst int
+    RateLimitPlaylist      int
+    RateLimitPlaylistBurst int
+    RateLimitWS            int
+    RateLimitWSBurst       int
     
     // Download
     DownloadDir     string
     FilePermissions os.FileMode
     
+    // Cleanup automático: intervalo e porcentagem dos downloads removidos
+    CleanupInterval time.Duration
+    CleanupPercent  int
+    
     // yt-dlp
+    YTDLPBinary         string
     ConcurrentFragments int
     FragmentRetries     int
     Retries            int
     ExtractorRetries   int
     DefaultQualityYTDLP int
     
+    // Jobs
+    JobWorkers  int
+    JobMaxQueue int
+    // Máximo de itens selecionados por index
+    MaxPlaylistItems int
+    
     // Templates
     OutputTemplateSingle   string
     OutputTemplatePlaylist string
-    ProgressTemplate       string
     
     // Defaults
     DefaultFormat   string
@@ -42,56 +105,229 @@ type Config struct {
     DefaultIndex    string
 }
 
+// Load carrega a configuração do arquivo em YTAPI_CONFIG, se definido
 func Load() *Config {
-    // Carrega o arquivo .env se existir
-    if err := godotenv.Load(); err != nil {
+    return LoadFile("")
+}
+
+// LoadFile parte de Default, aplica o arquivo YAML em path (ou em
+// YTAPI_CONFIG, se path for vazio) e por fim as variáveis de ambiente (e do
+// .env) definidas, que têm precedência sobre o arquivo. Erros de leitura e
+// valores com tipo errado são reportados por Validate
+func LoadFile(path string) *Config {
+    // O .env é lido a cada carga (e não copiado para o ambiente do
+    // processo) para que um reload veja as alterações no arquivo
+    dotenv, err := godotenv.Read()
+    if err != nil {
         log.Println("Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
     }
+    env := &envReader{dotenv: dotenv}
+    
+    cfg := Default()
+    if path == "" {
+        path = env.getenv("YTAPI_CONFIG")
+    }
+    cfg.source = path
+    if path != "" {
+        if err := cfg.loadFile(path); err != nil {
+            cfg.loadErrors = append(cfg.loadErrors, err.Error())
+        }
+    }
+    
+    // Server
+    env.string(&cfg.GinMode, "GIN_MODE")
+    env.string(&cfg.Port, "PORT")
+    env.string(&cfg.Host, "HOST")
+    env.duration(&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT")
+    
+    // Handlers
+    env.string(&cfg.DownloadHandler, "DOWNLOAD_HANDLER")
+    env.string(&cfg.PlaylistHandler, "PLAYLIST_HANDLER")
+    env.string(&cfg.WebSocketHandler, "WEBSOCKET_HANDLER")
+    env.string(&cfg.JobsHandler, "JOBS_HANDLER")
+    env.string(&cfg.EventsHandler, "EVENTS_HANDLER")
+    env.string(&cfg.FetchHandler, "FETCH_HANDLER")
+    env.string(&cfg.AdminHandler, "ADMIN_HANDLER")
+    
+    // Segurança
+    env.list(&cfg.APIKeys, "API_KEYS")
+    env.string(&cfg.APIKeysFile, "API_KEYS_FILE")
+    env.list(&cfg.WSAllowedOrigins, "WS_ALLOWED_ORIGINS")
+    env.list(&cfg.TrustedProxies, "TRUSTED_PROXIES")
+    env.list(&cfg.AdminKeys, "ADMIN_KEYS")
+    
+    // Cotas padrão por chave
+    env.int(&cfg.APIKeyMaxJobs, "API_KEY_MAX_JOBS")
+    env.int(&cfg.APIKeyMaxDaily, "API_KEY_MAX_DAILY")
+    env.int64(&cfg.APIKeyMaxBytes, "API_KEY_MAX_BYTES")
+    
+    // Política de URLs
+    env.list(&cfg.URLAllowedSchemes, "URL_ALLOWED_SCHEMES")
+    env.list(&cfg.URLAllowedHosts, "URL_ALLOWED_HOSTS")
+    env.list(&cfg.URLDeniedHosts, "URL_DENIED_HOSTS")
+    env.bool(&cfg.URLAllowPrivate, "URL_ALLOW_PRIVATE")
+    env.list(&cfg.URLAllowedExtractors, "URL_ALLOWED_EXTRACTORS")
+    env.bool(&cfg.URLAllowGeneric, "URL_ALLOW_GENERIC")
+    
+    // Rate limit
+    env.int(&cfg.RateLimitDownload, "RATE_LIMIT_DOWNLOAD")
+    env.int(&cfg.RateLimitDownloadBurst, "RATE_LIMIT_DOWNLOAD_BURST")
+    env.int(&cfg.RateLimitPlaylist, "RATE_LIMIT_PLAYLIST")
+    env.int(&cfg.RateLimitPlaylistBurst, "RATE_LIMIT_PLAYLIST_BURST")
+    env.int(&cfg.RateLimitWS, "RATE_LIMIT_WS")
+    env.int(&cfg.RateLimitWSBurst, "RATE_LIMIT_WS_BURST")
+    
+    // Download
+    env.string(&cfg.DownloadDir, "DOWNLOAD_DIR")
+    env.fileMode(&cfg.FilePermissions, "FILE_PERMISSIONS")
+    
+    // Cleanup
+    env.duration(&cfg.CleanupInterval, "CLEANUP_INTERVAL")
+    env.int(&cfg.CleanupPercent, "CLEANUP_PERCENT")
+    
+    // yt-dlp
+    env.string(&cfg.YTDLPBinary, "YTDLP_BINARY")
+    env.int(&cfg.ConcurrentFragments, "YTDLP_CONCURRENT_FRAGMENTS")
+    env.int(&cfg.FragmentRetries, "YTDLP_FRAGMENT_RETRIES")
+    env.int(&cfg.Retries, "YTDLP_RETRIES")
+    env.int(&cfg.ExtractorRetries, "YTDLP_EXTRACTOR_RETRIES")
+    env.int(&cfg.DefaultQualityYTDLP, "YTDLP_DEFAULT_QUALITY")
+    
+    // Jobs
+    env.int(&cfg.JobWorkers, "JOB_WORKERS")
+    env.int(&cfg.JobMaxQueue, "JOB_MAX_QUEUE")
+    env.int(&cfg.MaxPlaylistItems, "MAX_PLAYLIST_ITEMS")
+    
+    // Templates
+    env.string(&cfg.OutputTemplateSingle, "OUTPUT_TEMPLATE_SINGLE")
+    env.string(&cfg.OutputTemplatePlaylist, "OUTPUT_TEMPLATE_PLAYLIST")
+    
+    // Defaults
+    env.string(&cfg.DefaultFormat, "DEFAULT_FORMAT")
+    env.string(&cfg.DefaultQuality, "DEFAULT_QUALITY")
+    env.string(&cfg.DefaultPlaylist, "DEFAULT_PLAYLIST")
+    env.string(&cfg.DefaultIndex, "DEFAULT_INDEX")
     
-    return &Config{
-        // Server
-        GinMode: getEnv("GIN_MODE"),
-        Port:    getEnv("PORT"),
-        Host:    getEnv("HOST"),
-        
-        // Handlers
-        DownloadHandler:  getEnv("DOWNLOAD_HANDLER"),
-        PlaylistHandler:  getEnv("PLAYLIST_HANDLER"),
-        WebSocketHandler: getEnv("WEBSOCKET_HANDLER"),
-        
-        // Download
-        DownloadDir:     getEnv("DOWNLOAD_DIR"),
-        FilePermissions: os.FileMode(getEnvInt("FILE_PERMISSIONS")),
-        
-        // yt-dlp
-        ConcurrentFragments: getEnvInt("YTDLP_CONCURRENT_FRAGMENTS"),
-        FragmentRetries:     getEnvInt("YTDLP_FRAGMENT_RETRIES"),
-        Retries:            getEnvInt("YTDLP_RETRIES"),
-        ExtractorRetries:   getEnvInt("YTDLP_EXTRACTOR_RETRIES"),
-        DefaultQualityYTDLP: getEnvInt("YTDLP_DEFAULT_QUALITY"),
-        
-        // Templates
-        OutputTemplateSingle:   getEnv("OUTPUT_TEMPLATE_SINGLE"),
-        OutputTemplatePlaylist: getEnv("OUTPUT_TEMPLATE_PLAYLIST"),
-        ProgressTemplate:       getEnv("PROGRESS_TEMPLATE"),
-        
-        // Defaults
-        DefaultFormat:   getEnv("DEFAULT_FORMAT"),
-        DefaultQuality:  getEnv("DEFAULT_QUALITY"),
-        DefaultPlaylist: getEnv("DEFAULT_PLAYLIST"),
-        DefaultIndex:    getEnv("DEFAULT_INDEX"),
+    cfg.loadErrors = append(cfg.loadErrors, env.errs...)
+    snapshot := *cfg
+    cfg.loaded = &snapshot
+    return cfg
+}
+
+// Override aplica fn à configuração e a registra para ser reaplicada a cada
+// reload, para que ajustes feitos em código continuem valendo sobre o
+// arquivo e o ambiente relidos
+func (c *Config) Override(fn func(*Config)) {
+    fn(c)
+    // Não compartilha o slice com cópias anteriores da configuração
+    c.overrides = append(c.overrides[:len(c.overrides):len(c.overrides)], fn)
+}
+
+// envReader sobrescreve os campos com as variáveis definidas e acumula os
+// erros de conversão. As variáveis do processo têm precedência sobre as do
+// .env, como no godotenv.Load
+type envReader struct {
+    errs   []string
+    dotenv map[string]string
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+    if value, ok := os.LookupEnv(key); ok {
+        return value, true
     }
+    value, ok := e.dotenv[key]
+    return value, ok
+}
+
+func (e *envReader) getenv(key string) string {
+    value, _ := e.lookup(key)
+    return value
 }
 
-func getEnv(key string) string {
-    return os.Getenv(key)
+func (e *envReader) string(dst *string, key string) {
+    if value := e.getenv(key); value != "" {
+        *dst = value
+    }
 }
 
-func getEnvInt(key string) int {
-    if value := os.Getenv(key); value != "" {
-        if intValue, err := strconv.Atoi(value); err == nil {
-            return intValue
+// Lista separada por vírgulas; itens vazios são descartados. Como nos
+// outros tipos, uma variável vazia (API_KEYS=) conta como não definida e não
+// apaga a lista do arquivo, o que desligaria a autenticação sem aviso
+func (e *envReader) list(dst *[]string, key string) {
+    var list []string
+    for _, item := range strings.Split(e.getenv(key), ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            list = append(list, item)
         }
     }
-    return 0
-}
\ No newline at end of file
+    if len(list) > 0 {
+        *dst = list
+    }
+}
+
+func (e *envReader) bool(dst *bool, key string) {
+    value := e.getenv(key)
+    if value == "" {
+        return
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        e.errs = append(e.errs, fmt.Sprintf("%s: %q não é true/false", key, value))
+        return
+    }
+    *dst = b
+}
+
+func (e *envReader) int(dst *int, key string) {
+    value := e.getenv(key)
+    if value == "" {
+        return
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil {
+        e.errs = append(e.errs, fmt.Sprintf("%s: %q não é um número inteiro", key, value))
+        return
+    }
+    *dst = n
+}
+
+func (e *envReader) int64(dst *int64, key string) {
+    value := e.getenv(key)
+    if value == "" {
+        return
+    }
+    n, err := strconv.ParseInt(value, 10, 64)
+    if err != nil {
+        e.errs = append(e.errs, fmt.Sprintf("%s: %q não é um número inteiro", key, value))
+        return
+    }
+    *dst = n
+}
+
+// Duração no formato de time.ParseDuration: "12h", "90m"
+func (e *envReader) duration(dst *time.Duration, key string) {
+    value := e.getenv(key)
+    if value == "" {
+        return
+    }
+    d, err := time.ParseDuration(value)
+    if err != nil {
+        e.errs = append(e.errs, fmt.Sprintf("%s: %q não é uma duração (ex.: 12h)", key, value))
+        return
+    }
+    *dst = d
+}
+
+// Permissões em octal, como no chmod: "0755" ou "755"
+func (e *envReader) fileMode(dst *os.FileMode, key string) {
+    value := e.getenv(key)
+    if value == "" {
+        return
+    }
+    mode, err := strconv.ParseUint(value, 8, 32)
+    if err != nil || mode > 0777 {
+        e.errs = append(e.errs, fmt.Sprintf("%s: %q não é uma permissão octal (ex.: 0755)", key, value))
+        return
+    }
+    *dst = os.FileMode(mode)
+}
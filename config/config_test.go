@@ -0,0 +1,55 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+// writeConfigFile grava o YAML em um arquivo temporário e retorna o caminho
+func writeConfigFile(t *testing.T, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestLoadFileEnvList(t *testing.T) {
+    path := writeConfigFile(t, `
+auth:
+  keys: ["alice:token-da-alice"]
+server:
+  allowedOrigins: ["https://app.exemplo.com"]
+`)
+    tests := []struct {
+        name    string
+        env     map[string]string
+        keys    []string
+        origins []string
+    }{
+        {"sem variáveis", nil, []string{"alice:token-da-alice"}, []string{"https://app.exemplo.com"}},
+        {"variável vazia mantém o arquivo", map[string]string{"API_KEYS": ""}, []string{"alice:token-da-alice"}, []string{"https://app.exemplo.com"}},
+        {"só separadores mantém o arquivo", map[string]string{"WS_ALLOWED_ORIGINS": " , "}, []string{"alice:token-da-alice"}, []string{"https://app.exemplo.com"}},
+        {"variável substitui o arquivo", map[string]string{"API_KEYS": "bob:token-do-bob, carol:token-da-carol"}, []string{"bob:token-do-bob", "carol:token-da-carol"}, []string{"https://app.exemplo.com"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            for key, value := range tt.env {
+                t.Setenv(key, value)
+            }
+            cfg := LoadFile(path)
+            if err := cfg.Validate(); err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(cfg.APIKeys, tt.keys) {
+                t.Errorf("APIKeys = %q, esperado %q", cfg.APIKeys, tt.keys)
+            }
+            if !reflect.DeepEqual(cfg.WSAllowedOrigins, tt.origins) {
+                t.Errorf("WSAllowedOrigins = %q, esperado %q", cfg.WSAllowedOrigins, tt.origins)
+            }
+        })
+    }
+}
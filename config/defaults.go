@@ -0,0 +1,53 @@
+package config
+
+import "time"
+
+// Default retorna a configuração usada para tudo que não foi definido
+func Default() *Config {
+    return &Config{
+        // Server
+        GinMode:         "release",
+        Port:            "8080",
+        Host:            "0.0.0.0",
+        ShutdownTimeout: 30 * time.Second,
+        
+        // Handlers
+        DownloadHandler:  "/download",
+        PlaylistHandler:  "/playlist",
+        WebSocketHandler: "/ws",
+        JobsHandler:      "/jobs",
+        EventsHandler:    "/events",
+        FetchHandler:     "/fetch",
+        AdminHandler:     "/admin",
+        
+        // Download
+        DownloadDir:     "./downloads",
+        FilePermissions: 0755,
+        
+        // Cleanup
+        CleanupInterval: 12 * time.Hour,
+        CleanupPercent:  50,
+        
+        // yt-dlp
+        YTDLPBinary:         "yt-dlp",
+        ConcurrentFragments: 4,
+        FragmentRetries:     10,
+        Retries:             10,
+        ExtractorRetries:    3,
+        DefaultQualityYTDLP: 720,
+        
+        // Jobs
+        JobWorkers:       2,
+        JobMaxQueue:      50,
+        MaxPlaylistItems: 100,
+        
+        // Templates
+        OutputTemplateSingle:   "%(title)s.%(ext)s",
+        OutputTemplatePlaylist: "%(playlist_index)s - %(title)s.%(ext)s",
+        
+        // Defaults
+        DefaultFormat:   "mp4",
+        DefaultQuality:  "720p",
+        DefaultPlaylist: "false",
+    }
+}
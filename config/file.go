@@ -0,0 +1,320 @@
+package config
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// fileConfig é o formato do arquivo YAML, organizado em seções
+type fileConfig struct {
+    Server     serverSection     `yaml:"server"`
+    Downloader downloaderSection `yaml:"downloader"`
+    Storage    storageSection    `yaml:"storage"`
+    Cleanup    cleanupSection    `yaml:"cleanup"`
+    Auth       authSection       `yaml:"auth"`
+}
+
+type serverSection struct {
+    Mode            string           `yaml:"mode"`
+    Host            string           `yaml:"host"`
+    Port            string           `yaml:"port"`
+    ShutdownTimeout duration         `yaml:"shutdownTimeout"`
+    Routes          routesSection    `yaml:"routes"`
+    AllowedOrigins  []string         `yaml:"allowedOrigins"`
+    TrustedProxies  []string         `yaml:"trustedProxies"`
+    RateLimit       rateLimitSection `yaml:"rateLimit"`
+}
+
+type routesSection struct {
+    Download  string `yaml:"download"`
+    Playlist  string `yaml:"playlist"`
+    WebSocket string `yaml:"websocket"`
+    Jobs      string `yaml:"jobs"`
+    Events    string `yaml:"events"`
+    Fetch     string `yaml:"fetch"`
+    Admin     string `yaml:"admin"`
+}
+
+type rateLimitSection struct {
+    Download  rateSection `yaml:"download"`
+    Playlist  rateSection `yaml:"playlist"`
+    WebSocket rateSection `yaml:"websocket"`
+}
+
+type rateSection struct {
+    PerMinute int `yaml:"perMinute"`
+    Burst     int `yaml:"burst"`
+}
+
+type downloaderSection struct {
+    Binary              string           `yaml:"binary"`
+    ConcurrentFragments int              `yaml:"concurrentFragments"`
+    FragmentRetries     int              `yaml:"fragmentRetries"`
+    Retries             int              `yaml:"retries"`
+    ExtractorRetries    int              `yaml:"extractorRetries"`
+    DefaultHeight       int              `yaml:"defaultHeight"`
+    Workers             int              `yaml:"workers"`
+    MaxQueue            int              `yaml:"maxQueue"`
+    MaxPlaylistItems    int              `yaml:"maxPlaylistItems"`
+    Templates           templatesSection `yaml:"templates"`
+    Defaults            defaultsSection  `yaml:"defaults"`
+    URLs                urlPolicySection `yaml:"urls"`
+}
+
+type templatesSection struct {
+    Single   string `yaml:"single"`
+    Playlist string `yaml:"playlist"`
+}
+
+type defaultsSection struct {
+    Format   string `yaml:"format"`
+    Quality  string `yaml:"quality"`
+    Playlist string `yaml:"playlist"`
+    Index    string `yaml:"index"`
+}
+
+type urlPolicySection struct {
+    Schemes      []string `yaml:"schemes"`
+    AllowHosts   []string `yaml:"allowHosts"`
+    DenyHosts    []string `yaml:"denyHosts"`
+    AllowPrivate bool     `yaml:"allowPrivate"`
+    Extractors   []string `yaml:"extractors"`
+    AllowGeneric bool     `yaml:"allowGeneric"`
+}
+
+type storageSection struct {
+    Dir         string   `yaml:"dir"`
+    Permissions fileMode `yaml:"permissions"`
+}
+
+type cleanupSection struct {
+    Interval duration `yaml:"interval"`
+    Percent  int      `yaml:"percent"`
+}
+
+type authSection struct {
+    Keys      []string      `yaml:"keys"`
+    KeysFile  string        `yaml:"keysFile"`
+    AdminKeys []string      `yaml:"adminKeys"`
+    Limits    limitsSection `yaml:"limits"`
+}
+
+type limitsSection struct {
+    MaxConcurrentJobs int   `yaml:"maxConcurrentJobs"`
+    MaxDailyDownloads int   `yaml:"maxDailyDownloads"`
+    MaxBytes          int64 `yaml:"maxBytes"`
+}
+
+// fileMode é a permissão em octal, como no chmod ("0755")
+type fileMode os.FileMode
+
+func (m *fileMode) UnmarshalYAML(node *yaml.Node) error {
+    mode, err := strconv.ParseUint(node.Value, 8, 32)
+    if err != nil || mode > 0777 {
+        return fmt.Errorf("linha %d: %q não é uma permissão octal (ex.: \"0755\")", node.Line, node.Value)
+    }
+    *m = fileMode(mode)
+    return nil
+}
+
+func (m fileMode) MarshalYAML() (interface{}, error) {
+    return fmt.Sprintf("%#o", uint32(m)), nil
+}
+
+// duration aceita o formato de time.ParseDuration ("12h", "30m")
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(node *yaml.Node) error {
+    value, err := time.ParseDuration(node.Value)
+    if err != nil {
+        return fmt.Errorf("linha %d: %q não é uma duração (ex.: \"12h\")", node.Line, node.Value)
+    }
+    *d = duration(value)
+    return nil
+}
+
+func (d duration) MarshalYAML() (interface{}, error) {
+    return time.Duration(d).String(), nil
+}
+
+// loadFile sobrescreve a configuração com os campos presentes no arquivo.
+// Chaves desconhecidas são erro, para que um erro de digitação não passe
+// despercebido
+func (c *Config) loadFile(path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("lendo %s: %w", path, err)
+    }
+
+    file := newFileConfig(c)
+    decoder := yaml.NewDecoder(bytes.NewReader(data))
+    decoder.KnownFields(true)
+    if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+        return fmt.Errorf("%s: %w", path, err)
+    }
+    file.apply(c)
+    return nil
+}
+
+// MarshalYAML grava a configuração no formato do arquivo
+func (c *Config) MarshalYAML() (interface{}, error) {
+    return newFileConfig(c), nil
+}
+
+// Redacted retorna uma cópia com os segredos mascarados, para exibição
+func (c *Config) Redacted() *Config {
+    redacted := *c
+    redacted.APIKeys = make([]string, len(c.APIKeys))
+    for i, entry := range c.APIKeys {
+        // Sem o separador a entrada inteira pode ser o token
+        name, _, ok := strings.Cut(strings.TrimSpace(entry), ":")
+        if !ok {
+            name = ""
+        }
+        redacted.APIKeys[i] = name + ":********"
+    }
+    return &redacted
+}
+
+func newFileConfig(c *Config) fileConfig {
+    return fileConfig{
+        Server: serverSection{
+            Mode:            c.GinMode,
+            Host:            c.Host,
+            Port:            c.Port,
+            ShutdownTimeout: duration(c.ShutdownTimeout),
+            Routes: routesSection{
+                Download:  c.DownloadHandler,
+                Playlist:  c.PlaylistHandler,
+                WebSocket: c.WebSocketHandler,
+                Jobs:      c.JobsHandler,
+                Events:    c.EventsHandler,
+                Fetch:     c.FetchHandler,
+                Admin:     c.AdminHandler,
+            },
+            AllowedOrigins: c.WSAllowedOrigins,
+            TrustedProxies: c.TrustedProxies,
+            RateLimit: rateLimitSection{
+                Download:  rateSection{c.RateLimitDownload, c.RateLimitDownloadBurst},
+                Playlist:  rateSection{c.RateLimitPlaylist, c.RateLimitPlaylistBurst},
+                WebSocket: rateSection{c.RateLimitWS, c.RateLimitWSBurst},
+            },
+        },
+        Downloader: downloaderSection{
+            Binary:              c.YTDLPBinary,
+            ConcurrentFragments: c.ConcurrentFragments,
+            FragmentRetries:     c.FragmentRetries,
+            Retries:             c.Retries,
+            ExtractorRetries:    c.ExtractorRetries,
+            DefaultHeight:       c.DefaultQualityYTDLP,
+            Workers:             c.JobWorkers,
+            MaxQueue:            c.JobMaxQueue,
+            MaxPlaylistItems:    c.MaxPlaylistItems,
+            Templates: templatesSection{
+                Single:   c.OutputTemplateSingle,
+                Playlist: c.OutputTemplatePlaylist,
+            },
+            Defaults: defaultsSection{
+                Format:   c.DefaultFormat,
+                Quality:  c.DefaultQuality,
+                Playlist: c.DefaultPlaylist,
+                Index:    c.DefaultIndex,
+            },
+            URLs: urlPolicySection{
+                Schemes:      c.URLAllowedSchemes,
+                AllowHosts:   c.URLAllowedHosts,
+                DenyHosts:    c.URLDeniedHosts,
+                AllowPrivate: c.URLAllowPrivate,
+                Extractors:   c.URLAllowedExtractors,
+                AllowGeneric: c.URLAllowGeneric,
+            },
+        },
+        Storage: storageSection{
+            Dir:         c.DownloadDir,
+            Permissions: fileMode(c.FilePermissions),
+        },
+        Cleanup: cleanupSection{
+            Interval: duration(c.CleanupInterval),
+            Percent:  c.CleanupPercent,
+        },
+        Auth: authSection{
+            Keys:      c.APIKeys,
+            KeysFile:  c.APIKeysFile,
+            AdminKeys: c.AdminKeys,
+            Limits: limitsSection{
+                MaxConcurrentJobs: c.APIKeyMaxJobs,
+                MaxDailyDownloads: c.APIKeyMaxDaily,
+                MaxBytes:          c.APIKeyMaxBytes,
+            },
+        },
+    }
+}
+
+func (f fileConfig) apply(c *Config) {
+    // Server
+    c.GinMode = f.Server.Mode
+    c.Host = f.Server.Host
+    c.Port = f.Server.Port
+    c.ShutdownTimeout = time.Duration(f.Server.ShutdownTimeout)
+    c.DownloadHandler = f.Server.Routes.Download
+    c.PlaylistHandler = f.Server.Routes.Playlist
+    c.WebSocketHandler = f.Server.Routes.WebSocket
+    c.JobsHandler = f.Server.Routes.Jobs
+    c.EventsHandler = f.Server.Routes.Events
+    c.FetchHandler = f.Server.Routes.Fetch
+    c.AdminHandler = f.Server.Routes.Admin
+    c.WSAllowedOrigins = f.Server.AllowedOrigins
+    c.TrustedProxies = f.Server.TrustedProxies
+    c.RateLimitDownload = f.Server.RateLimit.Download.PerMinute
+    c.RateLimitDownloadBurst = f.Server.RateLimit.Download.Burst
+    c.RateLimitPlaylist = f.Server.RateLimit.Playlist.PerMinute
+    c.RateLimitPlaylistBurst = f.Server.RateLimit.Playlist.Burst
+    c.RateLimitWS = f.Server.RateLimit.WebSocket.PerMinute
+    c.RateLimitWSBurst = f.Server.RateLimit.WebSocket.Burst
+
+    // Downloader
+    d := f.Downloader
+    c.YTDLPBinary = d.Binary
+    c.ConcurrentFragments = d.ConcurrentFragments
+    c.FragmentRetries = d.FragmentRetries
+    c.Retries = d.Retries
+    c.ExtractorRetries = d.ExtractorRetries
+    c.DefaultQualityYTDLP = d.DefaultHeight
+    c.JobWorkers = d.Workers
+    c.JobMaxQueue = d.MaxQueue
+    c.MaxPlaylistItems = d.MaxPlaylistItems
+    c.OutputTemplateSingle = d.Templates.Single
+    c.OutputTemplatePlaylist = d.Templates.Playlist
+    c.DefaultFormat = d.Defaults.Format
+    c.DefaultQuality = d.Defaults.Quality
+    c.DefaultPlaylist = d.Defaults.Playlist
+    c.DefaultIndex = d.Defaults.Index
+    c.URLAllowedSchemes = d.URLs.Schemes
+    c.URLAllowedHosts = d.URLs.AllowHosts
+    c.URLDeniedHosts = d.URLs.DenyHosts
+    c.URLAllowPrivate = d.URLs.AllowPrivate
+    c.URLAllowedExtractors = d.URLs.Extractors
+    c.URLAllowGeneric = d.URLs.AllowGeneric
+
+    // Storage e cleanup
+    c.DownloadDir = f.Storage.Dir
+    c.FilePermissions = os.FileMode(f.Storage.Permissions)
+    c.CleanupInterval = time.Duration(f.Cleanup.Interval)
+    c.CleanupPercent = f.Cleanup.Percent
+
+    // Auth
+    c.APIKeys = f.Auth.Keys
+    c.APIKeysFile = f.Auth.KeysFile
+    c.AdminKeys = f.Auth.AdminKeys
+    c.APIKeyMaxJobs = f.Auth.Limits.MaxConcurrentJobs
+    c.APIKeyMaxDaily = f.Auth.Limits.MaxDailyDownloads
+    c.APIKeyMaxBytes = f.Auth.Limits.MaxBytes
+}
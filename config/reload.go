@@ -0,0 +1,190 @@
+package config
+
+import (
+    "errors"
+    "log"
+    "os"
+    "os/signal"
+    "reflect"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "syscall"
+)
+
+// Campos que podem mudar com o servidor rodando. Valem para os próximos
+// jobs e requisições; os demais (host, porta, rotas, diretórios, workers,
+// chaves...) só mudam com restart
+var reloadable = map[string]bool{
+    // Defaults do /download
+    "DefaultFormat":    true,
+    "DefaultQuality":   true,
+    "DefaultPlaylist":  true,
+    "DefaultIndex":     true,
+    "MaxPlaylistItems": true,
+
+    // yt-dlp
+    "YTDLPBinary":            true,
+    "ConcurrentFragments":    true,
+    "FragmentRetries":        true,
+    "Retries":                true,
+    "ExtractorRetries":       true,
+    "DefaultQualityYTDLP":    true,
+    "OutputTemplateSingle":   true,
+    "OutputTemplatePlaylist": true,
+
+    // Cleanup
+    "CleanupInterval": true,
+    "CleanupPercent":  true,
+
+    // Rate limit
+    "RateLimitDownload":      true,
+    "RateLimitDownloadBurst": true,
+    "RateLimitPlaylist":      true,
+    "RateLimitPlaylistBurst": true,
+    "RateLimitWS":            true,
+    "RateLimitWSBurst":       true,
+}
+
+// ErrNotReloadable é retornado pelo reload de uma configuração criada em
+// código, que não veio de LoadFile
+var ErrNotReloadable = errors.New("configuração criada em código, sem arquivo ou ambiente para reler")
+
+// Holder guarda a configuração em uso. Get é seguro entre goroutines e
+// sempre retorna uma configuração completa: o reload troca o ponteiro
+// inteiro, nunca altera a configuração já publicada
+type Holder struct {
+    current atomic.Pointer[Config]
+
+    // Serializa os reloads
+    mu sync.Mutex
+    // Separado de mu para que um listener (ou quem o registra segurando um
+    // lock que o listener também toma) nunca espere um reload em andamento
+    listenersMu sync.Mutex
+    listeners   []func(*Config)
+    logger    *log.Logger
+    // Configuração recebida em NewHolder, referência para o reload
+    base *Config
+}
+
+// ReloadResult lista os campos alterados no reload
+type ReloadResult struct {
+    // Campos aplicados
+    Applied []string `json:"applied"`
+    // Campos alterados no arquivo ou ambiente que exigem restart; continuam
+    // com o valor antigo
+    Rejected []string `json:"rejected"`
+}
+
+// NewHolder publica cfg. Os reloads são registrados em logger (nil usa o
+// logger padrão)
+func NewHolder(cfg *Config, logger *log.Logger) *Holder {
+    if logger == nil {
+        logger = log.Default()
+    }
+    h := &Holder{logger: logger, base: cfg}
+    h.current.Store(cfg)
+    return h
+}
+
+// Get retorna a configuração em uso
+func (h *Holder) Get() *Config {
+    return h.current.Load()
+}
+
+// OnReload registra fn para ser chamada com a nova configuração a cada
+// reload que altere algum campo. fn roda dentro do Reload e não deve
+// chamar Reload
+func (h *Holder) OnReload(fn func(*Config)) {
+    h.listenersMu.Lock()
+    defer h.listenersMu.Unlock()
+    h.listeners = append(h.listeners, fn)
+}
+
+// Reload lê de novo o arquivo de configuração e o ambiente, mantém os campos
+// alterados em código depois do LoadFile, reaplica os ajustes de Override,
+// valida e aplica os campos recarregáveis. Uma configuração criada em código
+// não é recarregada (ErrNotReloadable). Alterações que exigem restart são ignoradas com
+// um aviso no log. Com configuração inválida nada é aplicado
+func (h *Holder) Reload() (*ReloadResult, error) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    current := h.Get()
+    if h.base.loaded == nil {
+        h.logger.Printf("Reload ignorado: %v", ErrNotReloadable)
+        return nil, ErrNotReloadable
+    }
+    next := LoadFile(current.source)
+    keepCodeChanges(next, h.base)
+    for _, fn := range current.overrides {
+        fn(next)
+    }
+    if err := next.Validate(); err != nil {
+        h.logger.Printf("Reload da configuração recusado: %v", err)
+        return nil, err
+    }
+
+    merged := *current
+    result := &ReloadResult{Applied: []string{}, Rejected: []string{}}
+    dst := reflect.ValueOf(&merged).Elem()
+    src := reflect.ValueOf(next).Elem()
+    for i := 0; i < dst.NumField(); i++ {
+        field := dst.Type().Field(i)
+        if !field.IsExported() || reflect.DeepEqual(dst.Field(i).Interface(), src.Field(i).Interface()) {
+            continue
+        }
+        if !reloadable[field.Name] {
+            result.Rejected = append(result.Rejected, field.Name)
+            continue
+        }
+        dst.Field(i).Set(src.Field(i))
+        result.Applied = append(result.Applied, field.Name)
+    }
+
+    if len(result.Rejected) > 0 {
+        h.logger.Printf("⚠️  Reload: alterações que exigem restart foram ignoradas: %s", strings.Join(result.Rejected, ", "))
+    }
+    if len(result.Applied) == 0 {
+        h.logger.Println("Reload: nenhuma alteração aplicável")
+        return result, nil
+    }
+
+    h.current.Store(&merged)
+    h.listenersMu.Lock()
+    listeners := append([]func(*Config){}, h.listeners...)
+    h.listenersMu.Unlock()
+    for _, fn := range listeners {
+        fn(&merged)
+    }
+    h.logger.Printf("🔄 Configuração recarregada: %s", strings.Join(result.Applied, ", "))
+    return result, nil
+}
+
+// keepCodeChanges copia para next os campos de base que foram alterados em
+// código depois do LoadFile, para que o reload não os troque pelos valores
+// do arquivo, do ambiente ou padrão
+func keepCodeChanges(next, base *Config) {
+    loaded := reflect.ValueOf(base.loaded).Elem()
+    src := reflect.ValueOf(base).Elem()
+    dst := reflect.ValueOf(next).Elem()
+    for i := 0; i < src.NumField(); i++ {
+        if !src.Type().Field(i).IsExported() {
+            continue
+        }
+        if !reflect.DeepEqual(src.Field(i).Interface(), loaded.Field(i).Interface()) {
+            dst.Field(i).Set(src.Field(i))
+        }
+    }
+}
+
+// ReloadOnSignal recarrega a configuração a cada SIGHUP
+func (h *Holder) ReloadOnSignal() {
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGHUP)
+    go func() {
+        for range signals {
+            h.Reload()
+        }
+    }()
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+    "errors"
+    "io"
+    "log"
+    "os"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func newTestHolder(t *testing.T, content string) (*Holder, string) {
+    t.Helper()
+    path := writeConfigFile(t, content)
+    cfg := LoadFile(path)
+    if err := cfg.Validate(); err != nil {
+        t.Fatal(err)
+    }
+    return NewHolder(cfg, log.New(io.Discard, "", 0)), path
+}
+
+func TestHolderReload(t *testing.T) {
+    h, path := newTestHolder(t, `
+server:
+  port: "8080"
+cleanup:
+  interval: 1h
+`)
+    var notified []*Config
+    h.OnReload(func(cfg *Config) {
+        notified = append(notified, cfg)
+    })
+    before := h.Get()
+
+    // Campos recarregáveis são aplicados; os que exigem restart ficam
+    if err := os.WriteFile(path, []byte(`
+server:
+  port: "9090"
+cleanup:
+  interval: 2h
+`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    result, err := h.Reload()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(result.Applied, []string{"CleanupInterval"}) || !reflect.DeepEqual(result.Rejected, []string{"Port"}) {
+        t.Fatalf("Reload = %+v, esperado CleanupInterval aplicado e Port recusado", result)
+    }
+    cfg := h.Get()
+    if cfg.CleanupInterval != 2*time.Hour || cfg.Port != "8080" {
+        t.Fatalf("configuração depois do reload: intervalo %s, porta %s", cfg.CleanupInterval, cfg.Port)
+    }
+    if before.CleanupInterval != time.Hour {
+        t.Fatal("reload alterou a configuração já publicada")
+    }
+    if len(notified) != 1 || notified[0] != cfg {
+        t.Fatalf("listeners chamados %d vezes, esperado 1 com a nova configuração", len(notified))
+    }
+
+    // Configuração inválida é recusada por inteiro
+    if err := os.WriteFile(path, []byte(`
+cleanup:
+  interval: 2h
+  percent: 200
+downloader:
+  retries: 5
+`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    var invalid *ValidationError
+    if _, err := h.Reload(); !errors.As(err, &invalid) {
+        t.Fatalf("Reload inválido: erro = %v, esperado ValidationError", err)
+    }
+    if h.Get() != cfg || len(notified) != 1 {
+        t.Fatal("configuração inválida foi aplicada")
+    }
+}
+
+func TestHolderReloadFromCode(t *testing.T) {
+    h := NewHolder(Default(), log.New(io.Discard, "", 0))
+    if _, err := h.Reload(); !errors.Is(err, ErrNotReloadable) {
+        t.Fatalf("erro = %v, esperado %v", err, ErrNotReloadable)
+    }
+}
+
+func TestHolderOnReloadDuringReload(t *testing.T) {
+    h, path := newTestHolder(t, "cleanup:\n  interval: 1h\n")
+
+    // Um listener que espera quem registra listeners segurando um lock que
+    // ele também toma (como o cleanup do Storage) não pode travar o reload
+    registered := make(chan struct{})
+    h.OnReload(func(*Config) {
+        go func() {
+            h.OnReload(func(*Config) {})
+            close(registered)
+        }()
+        select {
+        case <-registered:
+        case <-time.After(5 * time.Second):
+            t.Error("OnReload bloqueado pelo reload em andamento")
+        }
+    })
+
+    if err := os.WriteFile(path, []byte("cleanup:\n  interval: 2h\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := h.Reload(); err != nil {
+        t.Fatal(err)
+    }
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+    "fmt"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/validate"
+)
+
+// ValidationError reúne todos os problemas da configuração
+type ValidationError struct {
+    Problems []string
+}
+
+func (e *ValidationError) Error() string {
+    return "configuração inválida:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate confere tipos e valores de todos os campos e retorna um
+// *ValidationError com todos os problemas encontrados
+func (c *Config) Validate() error {
+    problems := append([]string(nil), c.loadErrors...)
+    fail := func(format string, args ...any) {
+        problems = append(problems, fmt.Sprintf(format, args...))
+    }
+
+    // Server
+    switch c.GinMode {
+    case "debug", "release", "test":
+    default:
+        fail("GIN_MODE: %q deve ser debug, release ou test", c.GinMode)
+    }
+    if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+        fail("PORT: %q deve ser uma porta entre 1 e 65535", c.Port)
+    }
+
+    for _, proxy := range c.TrustedProxies {
+        if net.ParseIP(proxy) == nil {
+            if _, _, err := net.ParseCIDR(proxy); err != nil {
+                fail("TRUSTED_PROXIES: %q não é um IP nem um CIDR", proxy)
+            }
+        }
+    }
+
+    // Handlers: caminhos absolutos e distintos
+    routes := map[string]string{}
+    for _, route := range []struct{ key, path string }{
+        {"DOWNLOAD_HANDLER", c.DownloadHandler},
+        {"PLAYLIST_HANDLER", c.PlaylistHandler},
+        {"WEBSOCKET_HANDLER", c.WebSocketHandler},
+        {"JOBS_HANDLER", c.JobsHandler},
+        {"EVENTS_HANDLER", c.EventsHandler},
+        {"FETCH_HANDLER", c.FetchHandler},
+        {"ADMIN_HANDLER", c.AdminHandler},
+    } {
+        if !strings.HasPrefix(route.path, "/") || route.path == "/" {
+            fail("%s: %q deve começar com / e não pode ser a raiz", route.key, route.path)
+            continue
+        }
+        if other, ok := routes[route.path]; ok {
+            fail("%s: %q já é usado por %s", route.key, route.path, other)
+        }
+        routes[route.path] = route.key
+    }
+
+    if c.ShutdownTimeout < 0 {
+        fail("SHUTDOWN_TIMEOUT: %s não pode ser negativo", c.ShutdownTimeout)
+    }
+
+    // Download
+    if strings.TrimSpace(c.DownloadDir) == "" {
+        fail("DOWNLOAD_DIR: não pode ser vazio")
+    } else if info, err := os.Stat(c.DownloadDir); err == nil && !info.IsDir() {
+        fail("DOWNLOAD_DIR: %q não é um diretório", c.DownloadDir)
+    }
+    // O dono precisa ler, gravar e entrar nos diretórios criados
+    if c.FilePermissions&0700 != 0700 || c.FilePermissions > 0777 {
+        fail("FILE_PERMISSIONS: %#o deve dar rwx ao dono (ex.: 0755)", uint32(c.FilePermissions))
+    }
+
+    // Cleanup
+    if c.CleanupInterval < time.Minute {
+        fail("CLEANUP_INTERVAL: %s deve ser de pelo menos 1m", c.CleanupInterval)
+    }
+    if c.CleanupPercent < 1 || c.CleanupPercent > 100 {
+        fail("CLEANUP_PERCENT: %d deve estar entre 1 e 100", c.CleanupPercent)
+    }
+
+    // yt-dlp
+    if c.YTDLPBinary == "" {
+        fail("YTDLP_BINARY: não pode ser vazio")
+    }
+    positive(fail, "YTDLP_CONCURRENT_FRAGMENTS", c.ConcurrentFragments)
+    positive(fail, "YTDLP_DEFAULT_QUALITY", c.DefaultQualityYTDLP)
+    notNegative(fail, "YTDLP_FRAGMENT_RETRIES", c.FragmentRetries)
+    notNegative(fail, "YTDLP_RETRIES", c.Retries)
+    notNegative(fail, "YTDLP_EXTRACTOR_RETRIES", c.ExtractorRetries)
+
+    // Jobs
+    positive(fail, "JOB_WORKERS", c.JobWorkers)
+    positive(fail, "JOB_MAX_QUEUE", c.JobMaxQueue)
+    positive(fail, "MAX_PLAYLIST_ITEMS", c.MaxPlaylistItems)
+
+    // Cotas e rate limit: 0 desliga
+    notNegative(fail, "API_KEY_MAX_JOBS", c.APIKeyMaxJobs)
+    notNegative(fail, "API_KEY_MAX_DAILY", c.APIKeyMaxDaily)
+    if c.APIKeyMaxBytes < 0 {
+        fail("API_KEY_MAX_BYTES: %d não pode ser negativo", c.APIKeyMaxBytes)
+    }
+    notNegative(fail, "RATE_LIMIT_DOWNLOAD", c.RateLimitDownload)
+    notNegative(fail, "RATE_LIMIT_DOWNLOAD_BURST", c.RateLimitDownloadBurst)
+    notNegative(fail, "RATE_LIMIT_PLAYLIST", c.RateLimitPlaylist)
+    notNegative(fail, "RATE_LIMIT_PLAYLIST_BURST", c.RateLimitPlaylistBurst)
+    notNegative(fail, "RATE_LIMIT_WS", c.RateLimitWS)
+    notNegative(fail, "RATE_LIMIT_WS_BURST", c.RateLimitWSBurst)
+
+    // Templates
+    if c.OutputTemplateSingle == "" {
+        fail("OUTPUT_TEMPLATE_SINGLE: não pode ser vazio")
+    }
+    if c.OutputTemplatePlaylist == "" {
+        fail("OUTPUT_TEMPLATE_PLAYLIST: não pode ser vazio")
+    }
+
+    // Defaults dos parâmetros do /download
+    if _, err := validate.Format(c.DefaultFormat); err != nil {
+        fail("DEFAULT_FORMAT: %v", err)
+    }
+    if _, err := validate.Quality(c.DefaultQuality); err != nil {
+        fail("DEFAULT_QUALITY: %v", err)
+    }
+    if _, err := strconv.ParseBool(c.DefaultPlaylist); err != nil {
+        fail("DEFAULT_PLAYLIST: %q deve ser true ou false", c.DefaultPlaylist)
+    }
+    if c.DefaultIndex != "" {
+        if _, err := validate.Index(c.DefaultIndex, c.MaxPlaylistItems); err != nil {
+            fail("DEFAULT_INDEX: %v", err)
+        }
+    }
+
+    if len(problems) > 0 {
+        return &ValidationError{Problems: problems}
+    }
+    return nil
+}
+
+func positive(fail func(string, ...any), key string, value int) {
+    if value < 1 {
+        fail("%s: %d deve ser maior que zero", key, value)
+    }
+}
+
+func notNegative(fail func(string, ...any), key string, value int) {
+    if value < 0 {
+        fail("%s: %d não pode ser negativo", key, value)
+    }
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestValidate(t *testing.T) {
+    tests := []struct {
+        name   string
+        change func(*Config)
+        // Início do problema esperado; vazio para configuração válida
+        want string
+    }{
+        {"padrão", func(*Config) {}, ""},
+        {"gin mode", func(c *Config) { c.GinMode = "prod" }, "GIN_MODE"},
+        {"porta", func(c *Config) { c.Port = "80a" }, "PORT"},
+        {"proxy", func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/33"} }, "TRUSTED_PROXIES"},
+        {"rota sem barra", func(c *Config) { c.JobsHandler = "jobs" }, "JOBS_HANDLER"},
+        {"rota repetida", func(c *Config) { c.FetchHandler = c.DownloadHandler }, "FETCH_HANDLER"},
+        {"permissão sem rwx do dono", func(c *Config) { c.FilePermissions = 0555 }, "FILE_PERMISSIONS"},
+        {"intervalo do cleanup", func(c *Config) { c.CleanupInterval = time.Second }, "CLEANUP_INTERVAL"},
+        {"porcentagem do cleanup", func(c *Config) { c.CleanupPercent = 0 }, "CLEANUP_PERCENT"},
+        {"workers", func(c *Config) { c.JobWorkers = 0 }, "JOB_WORKERS"},
+        {"cota negativa", func(c *Config) { c.APIKeyMaxDaily = -1 }, "API_KEY_MAX_DAILY"},
+        {"template vazio", func(c *Config) { c.OutputTemplateSingle = "" }, "OUTPUT_TEMPLATE_SINGLE"},
+        {"format padrão", func(c *Config) { c.DefaultFormat = "flac" }, "DEFAULT_FORMAT"},
+        {"format padrão normalizado", func(c *Config) { c.DefaultFormat = "MP4" }, ""},
+        {"quality padrão", func(c *Config) { c.DefaultQuality = "721p" }, "DEFAULT_QUALITY"},
+        {"playlist padrão", func(c *Config) { c.DefaultPlaylist = "sim" }, "DEFAULT_PLAYLIST"},
+        {"playlist padrão numérica", func(c *Config) { c.DefaultPlaylist = "1" }, ""},
+        {"index padrão", func(c *Config) { c.DefaultIndex = "1;rm" }, "DEFAULT_INDEX"},
+        {"index padrão acima do limite", func(c *Config) { c.MaxPlaylistItems = 5; c.DefaultIndex = "1-6" }, "DEFAULT_INDEX"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := Default()
+            cfg.DownloadDir = t.TempDir()
+            tt.change(cfg)
+
+            err := cfg.Validate()
+            if tt.want == "" {
+                if err != nil {
+                    t.Fatalf("configuração válida recusada: %v", err)
+                }
+                return
+            }
+            var invalid *ValidationError
+            if !errors.As(err, &invalid) || len(invalid.Problems) != 1 || !strings.HasPrefix(invalid.Problems[0], tt.want+":") {
+                t.Fatalf("erro = %v, esperado um problema em %s", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+    cfg := Default()
+    cfg.DownloadDir = t.TempDir()
+    cfg.Port = "0"
+    cfg.DefaultFormat = "flac"
+    cfg.JobMaxQueue = -1
+
+    var invalid *ValidationError
+    if err := cfg.Validate(); !errors.As(err, &invalid) || len(invalid.Problems) != 3 {
+        t.Fatalf("erro = %v, esperado os 3 problemas de uma vez", err)
+    }
+}
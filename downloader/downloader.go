@@ -0,0 +1,35 @@
+package downloader
+
+import "context"
+
+// Request descreve um download a ser feito pelo engine
+type Request struct {
+	URL      string
+	Format   string
+	Quality  string
+	Playlist bool
+	Index    string
+	Dir      string
+}
+
+// Info é o resultado de Probe: metadados da URL sem baixar nada
+type Info struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Extractor  string `json:"extractor"`
+	IsPlaylist bool   `json:"isPlaylist"`
+	Count      int    `json:"count"`
+	Duration   int    `json:"duration"`
+}
+
+// Downloader abstrai o engine que baixa os vídeos (por padrão o yt-dlp)
+type Downloader interface {
+	// Download baixa um vídeo, ou os itens de Index de uma playlist, em req.Dir.
+	// onEvent (opcional) recebe os eventos de progresso na ordem em que ocorrem
+	Download(ctx context.Context, req Request, onEvent func(Event)) error
+	// DownloadPlaylist baixa a playlist inteira; um item que falha não
+	// interrompe os demais e gera um evento item_failed
+	DownloadPlaylist(ctx context.Context, req Request, onEvent func(Event)) error
+	// Probe consulta os metadados da URL
+	Probe(ctx context.Context, url string) (*Info, error)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package downloader
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup sem grupos de processos: encerra apenas o yt-dlp
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = 5 * time.Second
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package downloader
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup coloca o yt-dlp em um grupo de processos próprio para que,
+// ao cancelar o job, o ffmpeg e demais filhos também sejam encerrados
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+}
@@ -0,0 +1,182 @@
+package downloader
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// EventType identifica o tipo de evento de progresso
+type EventType string
+
+const (
+	EventItemStarted     EventType = "item_started"
+	EventItemProgress    EventType = "item_progress"
+	EventItemPostProcess EventType = "item_postprocess"
+	EventItemFinished    EventType = "item_finished"
+	EventItemFailed      EventType = "item_failed"
+)
+
+// Event é um evento de progresso de um item do download
+type Event struct {
+	Type  EventType
+	Index int // posição na playlist; 0 para vídeo único
+	Total int // total de itens da playlist; 0 se desconhecido
+	Title string
+
+	// Nome do arquivo (sem diretório), em item_progress e item_finished
+	Filename string
+
+	// item_progress
+	DownloadedBytes int64
+	TotalBytes      int64
+	Speed           float64 // bytes por segundo
+	ETA             int     // segundos
+
+	// item_postprocess: nome do pós-processador (Merger, ExtractAudio...)
+	Stage string
+
+	// item_failed
+	Error string
+}
+
+// Percent calcula o percentual baixado, ou -1 se o total é desconhecido
+func (e Event) Percent() float64 {
+	if e.TotalBytes <= 0 {
+		return -1
+	}
+	return float64(e.DownloadedBytes) * 100 / float64(e.TotalBytes)
+}
+
+// Prefixo das linhas geradas pelos templates abaixo, para não confundir com
+// a saída normal do yt-dlp
+const eventPrefix = "YTAPI "
+
+// Templates que fazem o yt-dlp emitir um JSON por linha. O default "|null"
+// garante JSON válido quando o campo não existe
+const (
+	itemStartedTemplate = `before_dl:` + eventPrefix + `{"type":"item_started","index":%(playlist_index|null)j,"total":%(n_entries|null)j,"title":%(title|null)j}`
+
+	itemFinishedTemplate = `after_move:` + eventPrefix + `{"type":"item_finished","index":%(playlist_index|null)j,"total":%(n_entries|null)j,"title":%(title|null)j,"filename":%(filepath|null)j}`
+
+	downloadProgressTemplate = `download:` + eventPrefix + `{"type":"item_progress","index":%(info.playlist_index|null)j,"total":%(info.n_entries|null)j,"title":%(info.title|null)j,` +
+		`"filename":%(progress.filename|null)j,"downloaded":%(progress.downloaded_bytes|null)j,"totalBytes":%(progress.total_bytes|null)j,` +
+		`"totalBytesEstimate":%(progress.total_bytes_estimate|null)j,"speed":%(progress.speed|null)j,"eta":%(progress.eta|null)j}`
+
+	postProcessTemplate = `postprocess:` + eventPrefix + `{"type":"item_postprocess","index":%(info.playlist_index|null)j,"total":%(info.n_entries|null)j,"title":%(info.title|null)j,` +
+		`"stage":%(progress.postprocessor|null)j,"status":%(progress.status|null)j}`
+)
+
+// progressArgs são as flags que ligam a saída em JSON
+func progressArgs() []string {
+	return []string{
+		"--newline",
+		"--progress",
+		"--no-simulate",
+		"--print", itemStartedTemplate,
+		"--print", itemFinishedTemplate,
+		"--progress-template", downloadProgressTemplate,
+		"--progress-template", postProcessTemplate,
+	}
+}
+
+// rawEvent é o JSON emitido pelos templates
+type rawEvent struct {
+	Type               EventType `json:"type"`
+	Index              *int      `json:"index"`
+	Total              *int      `json:"total"`
+	Title              *string   `json:"title"`
+	Filename           *string   `json:"filename"`
+	Downloaded         *float64  `json:"downloaded"`
+	TotalBytes         *float64  `json:"totalBytes"`
+	TotalBytesEstimate *float64  `json:"totalBytesEstimate"`
+	Speed              *float64  `json:"speed"`
+	ETA                *float64  `json:"eta"`
+	Stage              *string   `json:"stage"`
+	Status             *string   `json:"status"`
+}
+
+// Parser converte a saída do yt-dlp em eventos. Guarda o item atual para
+// associar as linhas de ERROR ao item que falhou
+type Parser struct {
+	current Event
+}
+
+// Parse interpreta uma linha de saída; retorna false se ela não gera evento
+func (p *Parser) Parse(line string) (Event, bool) {
+	line = strings.TrimSpace(line)
+
+	if strings.HasPrefix(line, "ERROR:") {
+		return Event{
+			Type:  EventItemFailed,
+			Index: p.current.Index,
+			Total: p.current.Total,
+			Title: p.current.Title,
+			Error: strings.TrimSpace(strings.TrimPrefix(line, "ERROR:")),
+		}, true
+	}
+
+	if !strings.HasPrefix(line, eventPrefix) {
+		return Event{}, false
+	}
+	var raw rawEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, eventPrefix)), &raw); err != nil {
+		return Event{}, false
+	}
+
+	event := Event{
+		Type:     raw.Type,
+		Index:    intValue(raw.Index),
+		Total:    intValue(raw.Total),
+		Title:    stringValue(raw.Title),
+		Filename: filepath.Base(stringValue(raw.Filename)),
+		Stage:    stringValue(raw.Stage),
+	}
+	if event.Filename == "." {
+		event.Filename = ""
+	}
+
+	switch raw.Type {
+	case EventItemStarted:
+		p.current = event
+	case EventItemProgress:
+		event.DownloadedBytes = int64(floatValue(raw.Downloaded))
+		event.TotalBytes = int64(floatValue(raw.TotalBytes))
+		if event.TotalBytes == 0 {
+			event.TotalBytes = int64(floatValue(raw.TotalBytesEstimate))
+		}
+		event.Speed = floatValue(raw.Speed)
+		event.ETA = int(floatValue(raw.ETA))
+	case EventItemPostProcess:
+		// Só o início de cada etapa interessa
+		if stringValue(raw.Status) != "started" {
+			return Event{}, false
+		}
+	case EventItemFinished:
+		p.current = Event{}
+	default:
+		return Event{}, false
+	}
+	return event, true
+}
+
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func floatValue(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func stringValue(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"testing"
+)
+
+func TestParserParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []Event
+	}{
+		{
+			name:  "linha comum do yt-dlp",
+			lines: []string{"[youtube] Extracting URL: https://youtube.com/watch?v=x"},
+		},
+		{
+			name:  "json inválido",
+			lines: []string{`YTAPI {"type":`},
+		},
+		{
+			name:  "tipo desconhecido",
+			lines: []string{`YTAPI {"type":"outro"}`},
+		},
+		{
+			name: "início e fim de um vídeo",
+			lines: []string{
+				`YTAPI {"type":"item_started","index":null,"total":null,"title":"Vídeo"}`,
+				`YTAPI {"type":"item_finished","index":null,"total":null,"title":"Vídeo","filename":"/tmp/dl_x/Vídeo.mp4"}`,
+			},
+			want: []Event{
+				{Type: EventItemStarted, Title: "Vídeo"},
+				{Type: EventItemFinished, Title: "Vídeo", Filename: "Vídeo.mp4"},
+			},
+		},
+		{
+			name: "progresso com total estimado",
+			lines: []string{
+				`  YTAPI {"type":"item_progress","index":2,"total":5,"title":"B","filename":"/d/2 - B.mp4.part","downloaded":512,"totalBytes":null,"totalBytesEstimate":2048.7,"speed":1000.5,"eta":3.9}  `,
+			},
+			want: []Event{
+				{Type: EventItemProgress, Index: 2, Total: 5, Title: "B", Filename: "2 - B.mp4.part", DownloadedBytes: 512, TotalBytes: 2048, Speed: 1000.5, ETA: 3},
+			},
+		},
+		{
+			name: "pós-processamento só no início",
+			lines: []string{
+				`YTAPI {"type":"item_postprocess","index":1,"total":2,"title":"A","stage":"Merger","status":"started"}`,
+				`YTAPI {"type":"item_postprocess","index":1,"total":2,"title":"A","stage":"Merger","status":"finished"}`,
+			},
+			want: []Event{
+				{Type: EventItemPostProcess, Index: 1, Total: 2, Title: "A", Stage: "Merger"},
+			},
+		},
+		{
+			name: "erro associado ao item atual",
+			lines: []string{
+				`YTAPI {"type":"item_started","index":3,"total":4,"title":"C"}`,
+				`ERROR: [youtube] abc: Video unavailable`,
+			},
+			want: []Event{
+				{Type: EventItemStarted, Index: 3, Total: 4, Title: "C"},
+				{Type: EventItemFailed, Index: 3, Total: 4, Title: "C", Error: "[youtube] abc: Video unavailable"},
+			},
+		},
+		{
+			name: "erro depois de um item terminado",
+			lines: []string{
+				`YTAPI {"type":"item_started","index":1,"total":2,"title":"A"}`,
+				`YTAPI {"type":"item_finished","index":1,"total":2,"title":"A","filename":"1 - A.mp4"}`,
+				`ERROR: Unable to download webpage`,
+			},
+			want: []Event{
+				{Type: EventItemStarted, Index: 1, Total: 2, Title: "A"},
+				{Type: EventItemFinished, Index: 1, Total: 2, Title: "A", Filename: "1 - A.mp4"},
+				{Type: EventItemFailed, Error: "Unable to download webpage"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parser Parser
+			var got []Event
+			for _, line := range tt.lines {
+				if event, ok := parser.Parse(line); ok {
+					got = append(got, event)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("eventos = %+v, esperado %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("evento %d = %+v, esperado %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEventPercent(t *testing.T) {
+	if got := (Event{DownloadedBytes: 50, TotalBytes: 200}).Percent(); got != 25 {
+		t.Errorf("Percent = %v, esperado 25", got)
+	}
+	if got := (Event{DownloadedBytes: 50}).Percent(); got != -1 {
+		t.Errorf("Percent sem total = %v, esperado -1", got)
+	}
+}
@@ -0,0 +1,243 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Arthur-Scaratti/yt-api/config"
+)
+
+// YTDLP é o Downloader que executa o binário do yt-dlp
+type YTDLP struct {
+	Binary string
+
+	ConcurrentFragments int
+	FragmentRetries     int
+	Retries             int
+	ExtractorRetries    int
+	DefaultQuality      int
+
+	// Extractors aceitos (--use-extractors); vazio usa todos os específicos
+	Extractors []string
+	// Com Extractors vazio, libera também o genérico, que baixa de qualquer
+	// página (e segue redirecionamentos para endereços internos)
+	AllowGeneric bool
+
+	OutputTemplateSingle   string
+	OutputTemplatePlaylist string
+}
+
+// NewYTDLP cria o Downloader a partir da configuração
+func NewYTDLP(cfg *config.Config) *YTDLP {
+	binary := cfg.YTDLPBinary
+	if binary == "" {
+		binary = "yt-dlp"
+	}
+	return &YTDLP{
+		Binary:                 binary,
+		ConcurrentFragments:    cfg.ConcurrentFragments,
+		FragmentRetries:        cfg.FragmentRetries,
+		Retries:                cfg.Retries,
+		ExtractorRetries:       cfg.ExtractorRetries,
+		DefaultQuality:         cfg.DefaultQualityYTDLP,
+		Extractors:             cfg.URLAllowedExtractors,
+		AllowGeneric:           cfg.URLAllowGeneric,
+		OutputTemplateSingle:   cfg.OutputTemplateSingle,
+		OutputTemplatePlaylist: cfg.OutputTemplatePlaylist,
+	}
+}
+
+func (y *YTDLP) Download(ctx context.Context, req Request, onEvent func(Event)) error {
+	cmdArgs := y.baseArgs(req, y.OutputTemplateSingle)
+
+	if req.Playlist {
+		if req.Index != "" {
+			cmdArgs = append(cmdArgs, "--playlist-items", req.Index)
+		}
+	} else {
+		cmdArgs = append(cmdArgs, "--no-playlist")
+	}
+	// "--" impede que a URL seja lida como opção
+	cmdArgs = append(cmdArgs, "--", req.URL)
+
+	return y.run(ctx, cmdArgs, onEvent, false)
+}
+
+func (y *YTDLP) DownloadPlaylist(ctx context.Context, req Request, onEvent func(Event)) error {
+	cmdArgs := y.baseArgs(req, y.OutputTemplatePlaylist)
+	// Um item indisponível não deve interromper o resto da playlist
+	cmdArgs = append(cmdArgs, "--ignore-errors")
+	cmdArgs = append(cmdArgs, "--", req.URL)
+
+	return y.run(ctx, cmdArgs, onEvent, true)
+}
+
+// run executa o yt-dlp convertendo a saída em eventos; as linhas que não são
+// eventos formam a mensagem de erro em caso de falha. Com partial, o código
+// de saída 1 que o yt-dlp usa quando algum item falhou com --ignore-errors
+// não é erro se ao menos um item terminou e as falhas já saíram como
+// item_failed
+func (y *YTDLP) run(ctx context.Context, cmdArgs []string, onEvent func(Event), partial bool) error {
+	cmd := y.command(ctx, cmdArgs...)
+
+	// stdout e stderr vão para o mesmo pipe para manter a ordem entre os
+	// eventos e as linhas de ERROR
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	err = cmd.Start()
+	writer.Close()
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var parser Parser
+	var output bytes.Buffer
+	var finished, failed int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := parser.Parse(line); ok {
+			if onEvent != nil {
+				onEvent(event)
+			}
+			switch event.Type {
+			case EventItemFinished:
+				finished++
+			case EventItemFailed:
+				failed++
+			}
+			if event.Type != EventItemFailed {
+				continue
+			}
+		}
+		output.WriteString(line)
+		output.WriteByte('\n')
+	}
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if partial && errors.As(err, &exitErr) && exitErr.ExitCode() == 1 &&
+		ctx.Err() == nil && finished > 0 && failed > 0 {
+		return nil
+	}
+	if err != nil {
+		return errors.New(output.String())
+	}
+	return nil
+}
+
+func (y *YTDLP) Probe(ctx context.Context, url string) (*Info, error) {
+	args := []string{"--dump-single-json", "--flat-playlist", "--no-warnings"}
+	args = append(args, y.extractorArgs()...)
+	cmd := y.command(ctx, append(args, "--", url)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.New(stderr.String())
+	}
+
+	var raw struct {
+		ID        string            `json:"id"`
+		Title     string            `json:"title"`
+		Type      string            `json:"_type"`
+		Extractor string            `json:"extractor_key"`
+		Duration  float64           `json:"duration"`
+		Entries   []json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("resposta inválida do yt-dlp: %w", err)
+	}
+	return &Info{
+		ID:         raw.ID,
+		Title:      raw.Title,
+		Extractor:  raw.Extractor,
+		IsPlaylist: raw.Type == "playlist",
+		Count:      len(raw.Entries),
+		Duration:   int(raw.Duration),
+	}, nil
+}
+
+// baseArgs monta as flags comuns a todos os downloads
+func (y *YTDLP) baseArgs(req Request, outputname string) []string {
+	cmdArgs := []string{
+		"--concurrent-fragments", strconv.Itoa(y.ConcurrentFragments),
+		"--fragment-retries", strconv.Itoa(y.FragmentRetries),
+		"--retries", strconv.Itoa(y.Retries),
+		"--extractor-retries", strconv.Itoa(y.ExtractorRetries),
+		"--continue",
+		"-o", outputname,
+		"-P", req.Dir,
+	}
+	cmdArgs = append(cmdArgs, progressArgs()...)
+	cmdArgs = append(cmdArgs, y.extractorArgs()...)
+
+	formatSelector := BuildFormatSelector(req.Format, ParseQuality(req.Quality, y.DefaultQuality))
+	cmdArgs = append(cmdArgs, "-f", formatSelector)
+
+	switch req.Format {
+	case "mp3":
+		cmdArgs = append(cmdArgs, "--extract-audio", "--audio-format", req.Format)
+	case "mp4", "mkv", "webm":
+		cmdArgs = append(cmdArgs, "--merge-output-format", req.Format)
+	}
+	return cmdArgs
+}
+
+// extractorArgs restringe os extractors do yt-dlp; sem lista, todos exceto o
+// genérico, a menos que AllowGeneric esteja ligado
+func (y *YTDLP) extractorArgs() []string {
+	switch {
+	case len(y.Extractors) > 0:
+		return []string{"--use-extractors", strings.Join(y.Extractors, ",")}
+	case !y.AllowGeneric:
+		return []string{"--use-extractors", "default,-generic"}
+	default:
+		return nil
+	}
+}
+
+func (y *YTDLP) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, y.Binary, args...)
+	setProcessGroup(cmd)
+	return cmd
+}
+
+func BuildFormatSelector(format string, height int) string {
+	switch format {
+	case "mp3":
+		return "bestaudio[ext=mp3]/bestaudio"
+	case "mp4", "mkv", "webm":
+		selector := fmt.Sprintf("bestvideo[height<=%d]+bestaudio/best", height)
+		selector += fmt.Sprintf("[ext=%s]", format)
+		return selector
+	default:
+		return "best"
+	}
+}
+
+// ParseQuality converte "720p" em 720; usa fallback se não reconhecer
+func ParseQuality(quality string, fallback int) int {
+	if strings.HasSuffix(quality, "p") {
+		q := strings.TrimSuffix(quality, "p")
+		if h, err := strconv.Atoi(q); err == nil {
+			return h
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+	"github.com/Arthur-Scaratti/yt-api/jobs"
+)
+
+// Version é a versão do schema das mensagens de progresso
+const Version = 1
+
+// Type identifica o tipo de mensagem
+type Type string
+
+const (
+	Snapshot     Type = "snapshot"
+	ItemProgress Type = "item_progress"
+	ItemDone     Type = "item_done"
+	ItemError    Type = "item_error"
+	JobDone      Type = "job_done"
+	JobFailed    Type = "job_failed"
+	JobCancelled Type = "job_cancelled"
+
+	// Respostas às mensagens de controle do WebSocket
+	Subscribed   Type = "subscribed"
+	Unsubscribed Type = "unsubscribed"
+	Error        Type = "error"
+)
+
+// Final indica se o tipo encerra o job (nenhuma mensagem vem depois)
+func (t Type) Final() bool {
+	return t == JobDone || t == JobFailed || t == JobCancelled
+}
+
+// Progress é o andamento do item em uma mensagem item_progress
+type Progress struct {
+	Percent float64 `json:"percent"`
+	Speed   float64 `json:"speed"` // bytes por segundo
+	ETA     int     `json:"eta"`   // segundos
+	Stage   string  `json:"stage,omitempty"`
+}
+
+// Message é a mensagem enviada aos clientes que acompanham um job
+type Message struct {
+	V     int       `json:"v"`
+	Seq   int64     `json:"seq"`
+	Type  Type      `json:"type"`
+	ID    jobid.ID  `json:"id,omitempty"`
+	Time  time.Time `json:"time"`
+	Index int       `json:"index,omitempty"`
+	Total int       `json:"total,omitempty"`
+	Title string    `json:"title,omitempty"`
+
+	// item_done: arquivo e URL para baixá-lo pelo PlaylistHandler
+	Filename string `json:"filename,omitempty"`
+	Download string `json:"download,omitempty"`
+
+	Progress *Progress `json:"progress,omitempty"`
+	Error    string    `json:"error,omitempty"`
+
+	// snapshot: estado do job no momento da conexão
+	Status *jobs.Status `json:"status,omitempty"`
+
+	// subscribed/unsubscribed: jobs afetados
+	IDs []string `json:"ids,omitempty"`
+}
+
+// New cria uma mensagem do tipo informado para o job
+func New(typ Type, id jobid.ID) Message {
+	return Message{
+		V:    Version,
+		Type: typ,
+		ID:   id,
+		Time: time.Now(),
+	}
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+// Hub distribui as mensagens de cada job para seus assinantes e guarda um
+// log por job para que quem chega atrasado receba o que perdeu
+type Hub struct {
+	mu        sync.Mutex
+	topics    map[jobid.ID]*topic
+	maxLog    int
+	retention time.Duration
+
+	// Assinantes de todos os jobs
+	all     map[int]func(Message)
+	nextAll int
+}
+
+type topic struct {
+	seq      int64
+	log      []Message
+	subs     map[int]func(Message)
+	nextSub  int
+	closedAt time.Time
+}
+
+// NewHub cria o hub; maxLog limita as mensagens guardadas por job e
+// retention é quanto tempo o log de um job encerrado é mantido
+func NewHub(maxLog int, retention time.Duration) *Hub {
+	return &Hub{
+		topics:    make(map[jobid.ID]*topic),
+		all:       make(map[int]func(Message)),
+		maxLog:    maxLog,
+		retention: retention,
+	}
+}
+
+// MaxLog é o máximo de mensagens guardadas por job, ou seja, o maior replay
+// que um assinante pode receber de uma vez
+func (h *Hub) MaxLog() int {
+	return h.maxLog
+}
+
+// Retention é quanto tempo o log de um job encerrado é mantido
+func (h *Hub) Retention() time.Duration {
+	return h.retention
+}
+
+// Publish numera a mensagem, guarda no log do job e entrega aos assinantes
+func (h *Hub) Publish(msg Message) Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.topic(msg.ID)
+	t.closedAt = time.Time{}
+	t.seq++
+	msg.Seq = t.seq
+	t.log = append(t.log, msg)
+	if h.maxLog > 0 && len(t.log) > h.maxLog {
+		t.log = t.log[len(t.log)-h.maxLog:]
+	}
+
+	for _, fn := range t.subs {
+		fn(msg)
+	}
+	for _, fn := range h.all {
+		fn(msg)
+	}
+	return msg
+}
+
+// Subscribe entrega para fn as mensagens do job com Seq maior que since e
+// depois as novas, sem perder nem repetir nenhuma. Retorna a função que
+// cancela a assinatura e se o job já foi encerrado (nada mais será publicado).
+// Assinar um job sem mensagens não cria estado: o tópico aberto para esperar
+// a primeira publicação é descartado com o último assinante
+func (h *Hub) Subscribe(id jobid.ID, since int64, fn func(Message)) (func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.topic(id)
+	for _, msg := range t.log {
+		if msg.Seq > since {
+			fn(msg)
+		}
+	}
+
+	subID := t.nextSub
+	t.nextSub++
+	t.subs[subID] = fn
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(t.subs, subID)
+		if len(t.subs) == 0 && t.seq == 0 && h.topics[id] == t {
+			delete(h.topics, id)
+		}
+	}
+	return unsubscribe, !t.closedAt.IsZero()
+}
+
+// SubscribeAll entrega para fn as novas mensagens de todos os jobs, sem
+// replay. Retorna a função que cancela a assinatura
+func (h *Hub) SubscribeAll(fn func(Message)) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subID := h.nextAll
+	h.nextAll++
+	h.all[subID] = fn
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.all, subID)
+	}
+}
+
+// Reset descarta o log de uma execução anterior do job, para que uma nova
+// execução com o mesmo id não reentregue as mensagens antigas (como o
+// job_failed de antes de um retry). A numeração continua de onde parou, então
+// quem retoma com since não perde as mensagens da nova execução
+func (h *Hub) Reset(id jobid.ID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[id]; ok {
+		t.log = nil
+		t.closedAt = time.Time{}
+	}
+}
+
+// Close marca o job como encerrado; o log fica disponível por retention e
+// depois é descartado. Jobs sem nada publicado são ignorados
+func (h *Hub) Close(id jobid.ID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if t, ok := h.topics[id]; ok && t.seq > 0 {
+		t.closedAt = now
+	}
+	for key, t := range h.topics {
+		if !t.closedAt.IsZero() && len(t.subs) == 0 && now.Sub(t.closedAt) > h.retention {
+			delete(h.topics, key)
+		}
+	}
+}
+
+func (h *Hub) topic(id jobid.ID) *topic {
+	t, ok := h.topics[id]
+	if !ok {
+		t = &topic{subs: make(map[int]func(Message))}
+		h.topics[id] = t
+	}
+	return t
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+// collect assina id a partir de since e devolve as mensagens recebidas
+func collect(h *Hub, id jobid.ID, since int64) (*[]Message, func(), bool) {
+	var got []Message
+	unsubscribe, closed := h.Subscribe(id, since, func(msg Message) {
+		got = append(got, msg)
+	})
+	return &got, unsubscribe, closed
+}
+
+func seqs(msgs []Message) []int64 {
+	out := make([]int64, len(msgs))
+	for i, msg := range msgs {
+		out[i] = msg.Seq
+	}
+	return out
+}
+
+func equalSeqs(got []Message, want ...int64) bool {
+	s := seqs(got)
+	if len(s) != len(want) {
+		return false
+	}
+	for i := range s {
+		if s[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHubReplay(t *testing.T) {
+	h := NewHub(3, time.Minute)
+	id := jobid.New("a")
+	for i := 0; i < 5; i++ {
+		h.Publish(New(ItemProgress, id))
+	}
+
+	// O log guarda só as maxLog mais recentes
+	got, unsubscribe, _ := collect(h, id, 0)
+	defer unsubscribe()
+	if !equalSeqs(*got, 3, 4, 5) {
+		t.Fatalf("replay = %v, esperado [3 4 5]", seqs(*got))
+	}
+
+	// since pula o que o cliente já recebeu e as novas chegam em ordem
+	late, unsubscribeLate, _ := collect(h, id, 4)
+	defer unsubscribeLate()
+	h.Publish(New(ItemProgress, id))
+	if !equalSeqs(*late, 5, 6) {
+		t.Fatalf("replay com since = %v, esperado [5 6]", seqs(*late))
+	}
+	if !equalSeqs(*got, 3, 4, 5, 6) {
+		t.Fatalf("assinante antigo recebeu %v, esperado [3 4 5 6]", seqs(*got))
+	}
+
+	// Outros jobs não entram no tópico
+	h.Publish(New(ItemProgress, jobid.New("b")))
+	if len(*got) != 4 {
+		t.Fatalf("mensagem de outro job entregue: %v", seqs(*got))
+	}
+}
+
+func TestHubReset(t *testing.T) {
+	h := NewHub(10, time.Minute)
+	id := jobid.New("a")
+	h.Publish(New(JobFailed, id))
+	h.Close(id)
+	h.Reset(id)
+
+	// A nova execução não reentrega o log antigo e a numeração continua
+	got, unsubscribe, closed := collect(h, id, 0)
+	defer unsubscribe()
+	if closed {
+		t.Fatal("job reiniciado aparece como encerrado")
+	}
+	if len(*got) != 0 {
+		t.Fatalf("replay depois do Reset = %v, esperado vazio", seqs(*got))
+	}
+	if msg := h.Publish(New(ItemProgress, id)); msg.Seq != 2 {
+		t.Fatalf("Seq depois do Reset = %d, esperado 2", msg.Seq)
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	h := NewHub(10, 0)
+	id := jobid.New("a")
+	h.Publish(New(JobDone, id))
+	h.Close(id)
+
+	got, unsubscribe, closed := collect(h, id, 0)
+	if !closed || len(*got) != 1 {
+		t.Fatalf("assinatura após Close: closed = %v, replay = %v", closed, seqs(*got))
+	}
+	unsubscribe()
+
+	// Sem assinantes, o log passa da retenção no próximo Close
+	time.Sleep(time.Millisecond)
+	h.Close(jobid.New("b"))
+	got, unsubscribe, closed = collect(h, id, 0)
+	defer unsubscribe()
+	if closed || len(*got) != 0 {
+		t.Fatalf("log mantido depois da retenção: closed = %v, replay = %v", closed, seqs(*got))
+	}
+
+	// Assinar um job sem mensagens não deixa tópico para trás
+	other := jobid.New("c")
+	_, unsubscribeOther, _ := collect(h, other, 0)
+	unsubscribeOther()
+	if _, ok := h.topics[other]; ok {
+		t.Fatal("tópico vazio mantido depois do último assinante")
+	}
+}
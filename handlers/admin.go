@@ -0,0 +1,55 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/Arthur-Scaratti/yt-api/config"
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    errCodeInvalidConfig = "invalid_config"
+    errCodeNotReloadable = "reload_unavailable"
+)
+
+// ReloadConfigHandler recarrega a configuração, como o SIGHUP, e responde
+// com os campos aplicados e os que exigem restart
+func (a *API) ReloadConfigHandler(c *gin.Context) {
+    if !a.authorizeAdmin(c) {
+        return
+    }
+
+    result, err := a.settings.Reload()
+    if errors.Is(err, config.ErrNotReloadable) {
+        abortWithError(c, http.StatusConflict, errCodeNotReloadable, err.Error())
+        return
+    }
+    if err != nil {
+        abortWithError(c, http.StatusUnprocessableEntity, errCodeInvalidConfig, err.Error())
+        return
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+// authorizeAdmin aceita só as chaves listadas em ADMIN_KEYS. Sem
+// autenticação ligada as rotas de administração ficam fechadas
+func (a *API) authorizeAdmin(c *gin.Context) bool {
+    key, ok := a.apiKey(c)
+    if !ok {
+        if a.keys.Enabled() {
+            respondUnauthorized(c)
+        } else {
+            abortWithError(c, http.StatusForbidden, errCodeForbidden, "Rotas de administração exigem API_KEYS e ADMIN_KEYS")
+        }
+        return false
+    }
+
+    for _, name := range a.settings.Get().AdminKeys {
+        if name == key.Name {
+            return true
+        }
+    }
+    abortWithError(c, http.StatusForbidden, errCodeForbidden, "Chave sem permissão de administração")
+    return false
+}
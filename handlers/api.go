@@ -0,0 +1,170 @@
+package handlers
+
+import (
+    "context"
+    "log"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/auth"
+    "github.com/Arthur-Scaratti/yt-api/config"
+    "github.com/Arthur-Scaratti/yt-api/downloader"
+    "github.com/Arthur-Scaratti/yt-api/events"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
+    "github.com/Arthur-Scaratti/yt-api/validate"
+    "github.com/gorilla/websocket"
+)
+
+// Intervalo entre as gravações do consumo das chaves em disco
+const usageFlushInterval = 10 * time.Second
+
+// API reúne as dependências dos handlers. Cada instância tem sua própria
+// configuração, fila, armazenamento e conexões, então duas podem rodar no
+// mesmo processo com DOWNLOAD_DIR diferentes
+type API struct {
+    // Configuração em uso; os campos recarregáveis mudam com o reload
+    settings *config.Holder
+    storage  Storage
+    jobQueue *jobs.Manager
+
+    // Engine dos downloads. Recriado no reload para aplicar os ajustes do
+    // yt-dlp aos próximos jobs, a menos que tenha sido trocado por SetDownloader
+    dlMu             sync.RWMutex
+    dl               downloader.Downloader
+    customDownloader bool
+
+    // Chaves aceitas; sem API_KEYS nem API_KEYS_FILE a autenticação fica desligada
+    keys *auth.Keys
+    // Consumo das cotas por chave
+    usage *auth.Usage
+    // Regras para as URLs recebidas antes de chegarem ao yt-dlp
+    urlPolicy *validate.URLPolicy
+
+    // Pub/sub das mensagens de progresso, compartilhado por WebSocket e SSE
+    hub           *events.Hub
+    upgrader      websocket.Upgrader
+    wsConnections *wsHub
+
+    logger *log.Logger
+
+    // Prefixo das rotas quando a API é montada dentro de outro router;
+    // entra nas URLs devolvidas aos clientes
+    basePath string
+}
+
+// New prepara os handlers com a configuração carregada: engine, chaves de
+// API, política de URLs e a fila de jobs. Com hub nil é criado um que
+// guarda até 2000 mensagens por job, por 1 hora após o fim do job; com
+// logger nil é usado o logger padrão do pacote log
+func New(settings *config.Holder, storage Storage, hub *events.Hub, logger *log.Logger) *API {
+    cfg := settings.Get()
+    if hub == nil {
+        hub = events.NewHub(2000, time.Hour)
+    }
+    if logger == nil {
+        logger = log.Default()
+    }
+    a := &API{
+        settings:      settings,
+        storage:       storage,
+        dl:            downloader.NewYTDLP(cfg),
+        hub:           hub,
+        wsConnections: newWSHub(),
+        logger:        logger,
+        urlPolicy: &validate.URLPolicy{
+            Schemes:      cfg.URLAllowedSchemes,
+            AllowHosts:   cfg.URLAllowedHosts,
+            DenyHosts:    cfg.URLDeniedHosts,
+            AllowPrivate: cfg.URLAllowPrivate,
+        },
+    }
+    a.upgrader = websocket.Upgrader{CheckOrigin: a.checkOrigin}
+    settings.OnReload(func(cfg *config.Config) {
+        a.dlMu.Lock()
+        defer a.dlMu.Unlock()
+        if !a.customDownloader {
+            a.dl = downloader.NewYTDLP(cfg)
+        }
+    })
+
+    var err error
+    a.keys, err = auth.NewKeys(cfg.APIKeys, cfg.APIKeysFile, auth.Limits{
+        MaxConcurrentJobs: cfg.APIKeyMaxJobs,
+        MaxDailyDownloads: cfg.APIKeyMaxDaily,
+        MaxBytes:          cfg.APIKeyMaxBytes,
+    }, logger)
+    if err != nil {
+        a.logger.Printf("Erro ao carregar chaves de API: %v", err)
+    }
+    a.usage, err = auth.OpenUsage(filepath.Join(cfg.DownloadDir, auth.UsageFileName), logger)
+    if err != nil {
+        a.logger.Printf("Erro ao ler consumo das chaves de API: %v", err)
+    }
+    a.usage.StartFlush(usageFlushInterval)
+
+    journal, err := jobs.OpenJournal(filepath.Join(cfg.DownloadDir, jobs.JournalDirName))
+    if err != nil {
+        a.logger.Printf("Journal de jobs indisponível, jobs pendentes não serão retomados: %v", err)
+    }
+    a.jobQueue = jobs.NewManager(cfg.JobWorkers, cfg.JobMaxQueue, journal, a.runJob, logger)
+    // O status de um job encerrado some junto com o log de progresso dele
+    a.jobQueue.SetRetention(hub.Retention())
+    // Um retry reaproveita o id: o log da execução anterior não vale mais
+    a.jobQueue.OnSubmit(func(job *jobs.Job) {
+        a.hub.Reset(job.ID)
+    })
+    return a
+}
+
+// SetDownloader troca o engine usado pelos próximos downloads (ex.: um fake em testes)
+func (a *API) SetDownloader(d downloader.Downloader) {
+    a.dlMu.Lock()
+    defer a.dlMu.Unlock()
+    a.dl = d
+    a.customDownloader = true
+}
+
+// SetBasePath define o prefixo em que as rotas foram registradas. Deve ser
+// chamado antes de o servidor começar a atender
+func (a *API) SetBasePath(p string) {
+    a.basePath = strings.TrimSuffix(p, "/")
+}
+
+// route retorna o caminho público de uma rota da configuração
+func (a *API) route(path string) string {
+    return a.basePath + path
+}
+
+// currentDownloader retorna o engine para um novo job
+func (a *API) currentDownloader() downloader.Downloader {
+    a.dlMu.RLock()
+    defer a.dlMu.RUnlock()
+    return a.dl
+}
+
+// ResumeJobs recoloca na fila os downloads interrompidos por um restart;
+// o yt-dlp continua a partir dos arquivos .part
+func (a *API) ResumeJobs() {
+    count, err := a.jobQueue.Resume()
+    if err != nil {
+        a.logger.Printf("Erro ao retomar jobs pendentes: %v", err)
+        return
+    }
+    if count > 0 {
+        a.logger.Printf("%d job(s) pendente(s) retomado(s)", count)
+    }
+}
+
+// Shutdown para de aceitar downloads, espera os jobs em andamento até o fim
+// de ctx (depois disso os processos do yt-dlp são encerrados e os jobs ficam
+// no journal para serem retomados) e fecha os WebSockets abertos
+func (a *API) Shutdown(ctx context.Context) error {
+    err := a.jobQueue.Shutdown(ctx)
+    a.wsConnections.closeAll()
+    if err := a.usage.Close(); err != nil {
+        a.logger.Printf("Erro ao gravar consumo das chaves de API: %v", err)
+    }
+    return err
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+
+    "github.com/Arthur-Scaratti/yt-api/auth"
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
+    "github.com/gin-gonic/gin"
+)
+
+// Chave do gin.Context onde o middleware guarda a chave autenticada
+const apiKeyContext = "apiKey"
+
+// Códigos do campo "code" das respostas de erro
+const (
+    errCodeUnauthorized = "unauthorized"
+    errCodeForbidden    = "forbidden"
+    errCodeQuota        = "quota_exceeded"
+    errCodeQueueFull    = "queue_full"
+    errCodeShuttingDown = "shutting_down"
+    errCodeInvalidID    = "invalid_id"
+    errCodeInvalidURL   = "invalid_url"
+    errCodeInvalidParam = "invalid_param"
+)
+
+// AuthMiddleware exige uma chave válida (Authorization: Bearer, subprotocolo
+// do WebSocket ou ?token=) e bloqueia chaves sem cota de bytes. Os bytes
+// de cada resposta são somados à cota da chave
+func (a *API) AuthMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !a.keys.Enabled() {
+            c.Next()
+            return
+        }
+
+        key, ok := a.keys.Lookup(auth.TokenFromRequest(c.Request))
+        if !ok {
+            respondUnauthorized(c)
+            return
+        }
+        if err := a.usage.CheckBytes(key); err != nil {
+            respondQuota(c, err)
+            return
+        }
+
+        c.Set(apiKeyContext, key)
+        c.Next()
+
+        a.usage.AddBytes(key.Name, int64(c.Writer.Size()))
+    }
+}
+
+// apiKey retorna a chave da requisição, autenticada pelo middleware ou,
+// se a rota foi registrada sem ele, pelo próprio token
+func (a *API) apiKey(c *gin.Context) (*auth.Key, bool) {
+    if value, ok := c.Get(apiKeyContext); ok {
+        return value.(*auth.Key), true
+    }
+    return a.keys.Lookup(auth.TokenFromRequest(c.Request))
+}
+
+// caller identifica quem fez a requisição. Com a autenticação desligada
+// todos são o mesmo dono anônimo ("")
+func (a *API) caller(c *gin.Context) (string, bool) {
+    if !a.keys.Enabled() {
+        return "", true
+    }
+    key, ok := a.apiKey(c)
+    if !ok {
+        return "", false
+    }
+    return key.Name, true
+}
+
+// canAccess indica se o dono pode acompanhar ou cancelar o job. Jobs que não
+// estão mais na fila só têm o log no hub e não são expostos com auth ligada
+func (a *API) canAccess(owner string, id jobid.ID) bool {
+    if !a.keys.Enabled() {
+        return true
+    }
+    job, ok := a.jobQueue.Get(id)
+    return ok && job.OwnedBy(owner)
+}
+
+func (a *API) canAccessJob(owner string, job *jobs.Job) bool {
+    return !a.keys.Enabled() || job.OwnedBy(owner)
+}
+
+// reserveDownload reserva um download na cota diária da chave antes de
+// aceitar a requisição; a reserva é desfeita com releaseDownload se o job
+// não for aceito
+func (a *API) reserveDownload(c *gin.Context) bool {
+    key, ok := a.apiKey(c)
+    if !ok {
+        return true
+    }
+    if err := a.usage.ReserveDownload(key); err != nil {
+        respondQuota(c, err)
+        return false
+    }
+    return true
+}
+
+// maxActiveJobs é o limite de jobs simultâneos da chave da requisição,
+// conferido pelo Submit da fila (0 = sem limite)
+func (a *API) maxActiveJobs(c *gin.Context) int {
+    key, ok := a.apiKey(c)
+    if !ok {
+        return 0
+    }
+    return key.MaxConcurrentJobs
+}
+
+// releaseDownload devolve o download reservado por reserveDownload
+func (a *API) releaseDownload(c *gin.Context) {
+    if key, ok := a.apiKey(c); ok {
+        a.usage.ReleaseDownload(key.Name)
+    }
+}
+
+// parseJobID valida o id recebido do cliente; ids fora do formato
+// respondem 400 e nunca chegam a caminhos de arquivo
+func parseJobID(c *gin.Context, raw string) (jobid.ID, bool) {
+    id, err := jobid.Parse(raw)
+    if err != nil {
+        abortWithError(c, http.StatusBadRequest, errCodeInvalidID, err.Error())
+        return "", false
+    }
+    return id, true
+}
+
+// checkOrigin aplica WS_ALLOWED_ORIGINS. Sem lista só a própria origem é
+// aceita; "*" libera qualquer origem
+func (a *API) checkOrigin(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if origin == "" {
+        // Clientes que não são navegadores não enviam Origin
+        return true
+    }
+
+    cfg := a.settings.Get()
+    if len(cfg.WSAllowedOrigins) == 0 {
+        u, err := url.Parse(origin)
+        return err == nil && strings.EqualFold(u.Host, r.Host)
+    }
+    for _, allowed := range cfg.WSAllowedOrigins {
+        if allowed == "*" || strings.EqualFold(allowed, origin) {
+            return true
+        }
+    }
+    return false
+}
+
+// abortWithError encerra a requisição com o corpo de erro padrão
+// {"error": mensagem, "code": código}
+func abortWithError(c *gin.Context, status int, code, message string) {
+    c.AbortWithStatusJSON(status, gin.H{"error": message, "code": code})
+}
+
+// respondUnauthorized encerra a requisição sem token válido
+func respondUnauthorized(c *gin.Context) {
+    abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Token ausente ou inválido")
+}
+
+// respondForbidden encerra a requisição a um job de outra chave
+func respondForbidden(c *gin.Context) {
+    abortWithError(c, http.StatusForbidden, errCodeForbidden, "Job não pertence a este token")
+}
+
+// respondQuota encerra a requisição de uma chave sem cota. A cota diária
+// informa em Retry-After quando volta a valer
+func respondQuota(c *gin.Context, err error) {
+    if errors.Is(err, auth.ErrDailyLimit) {
+        c.Header("Retry-After", fmt.Sprint(int(auth.NextDay().Seconds())+1))
+    }
+    abortWithError(c, http.StatusTooManyRequests, errCodeQuota, err.Error())
+}
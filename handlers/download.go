@@ -1,61 +1,104 @@
 package handlers
 
 import (
-    "crypto/sha256"
+    "errors"
     "fmt"
     "net/http"
     "os"
-    "os/exec"
     "path/filepath"
     "strconv"
     "strings"
     "github.com/Arthur-Scaratti/yt-api/utils"
-    "github.com/Arthur-Scaratti/yt-api/config"
+    "github.com/Arthur-Scaratti/yt-api/auth"
+    "github.com/Arthur-Scaratti/yt-api/downloader"
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
+    "github.com/Arthur-Scaratti/yt-api/validate"
     "github.com/gin-gonic/gin"
 )
 
-var cfg *config.Config
-
-func init() {
-    cfg = config.Load()
-}
-
-func createDownloadDir() error {
-    if _, err := os.Stat(cfg.DownloadDir); os.IsNotExist(err) {
-        return os.MkdirAll(cfg.DownloadDir, cfg.FilePermissions)
-    }
-    return nil
-}
-
-func DownloadHandler(c *gin.Context) {
-    createDownloadDir()
+func (a *API) DownloadHandler(c *gin.Context) {
+    a.storage.EnsureDir()
+    // Uma única leitura para a requisição toda, mesmo com reload no meio
+    cfg := a.settings.Get()
 
     videoURL := c.Query("url")
     format := c.DefaultQuery("format", cfg.DefaultFormat)
-    quality := c.DefaultQuery("quality", fmt.Sprintf(cfg.DefaultQuality, "p"))
+    quality := c.DefaultQuery("quality", cfg.DefaultQuality)
     playlist := c.DefaultQuery("playlist", cfg.DefaultPlaylist)
     index := c.DefaultQuery("index", cfg.DefaultIndex)
+    // Download único em background: responde 202 e o arquivo sai pelo FetchHandler
+    isAsync := strings.ToLower(c.Query("async")) == "true"
 
     
     if videoURL == "" {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Missing URL"})
         return
     }
+    if err := a.urlPolicy.Check(c.Request.Context(), videoURL); err != nil {
+        abortWithError(c, http.StatusBadRequest, errCodeInvalidURL, err.Error())
+        return
+    }
     
-    isPlaylist := strings.ToLower(playlist) == "true"
+    // Normaliza os parâmetros antes do hash para que requisições
+    // equivalentes compartilhem o mesmo id e cache
+    format, err := validate.Format(format)
+    if err != nil {
+        c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+            "error":   err.Error(),
+            "code":    errCodeInvalidParam,
+            "allowed": validate.Formats,
+        })
+        return
+    }
+    quality, err = validate.Quality(quality)
+    if err != nil {
+        c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+            "error":   err.Error(),
+            "code":    errCodeInvalidParam,
+            "allowed": validate.Qualities,
+        })
+        return
+    }
+    if !validate.UsesQuality(format) {
+        // mp3 e best ignoram a quality; fora do hash, qualquer valor cai no mesmo id
+        quality = ""
+    }
+    // Mesma leitura do DEFAULT_PLAYLIST no config.Validate (true, 1, T...);
+    // vazio é false
+    isPlaylist := false
+    if playlist != "" {
+        if isPlaylist, err = strconv.ParseBool(playlist); err != nil {
+            abortWithError(c, http.StatusBadRequest, errCodeInvalidParam, fmt.Sprintf("playlist %q deve ser true ou false", playlist))
+            return
+        }
+    }
+    playlist = strconv.FormatBool(isPlaylist)
+    if !isPlaylist {
+        // Fora de playlist o yt-dlp ignora o index
+        index = ""
+    }
+    if index != "" {
+        if index, err = validate.Index(index, cfg.MaxPlaylistItems); err != nil {
+            abortWithError(c, http.StatusBadRequest, errCodeInvalidParam, err.Error())
+            return
+        }
+    }
     isIndexSet := index != ""
 
-    hasher := sha256.New()
     inputString := fmt.Sprintf("%s|%s|%s|%s|%s", videoURL, format, quality, playlist, index)
-    hasher.Write([]byte(inputString))
-    id := fmt.Sprintf("dl_%x", hasher.Sum(nil))
+    id := jobid.New(inputString)
+
+    if !a.reserveDownload(c) {
+        return
+    }
 
 	    // VERIFICAÇÃO SE ID JÁ EXISTE
-		if utils.CheckExistingID(id) {
+		if a.storage.CheckExistingID(id) {
 			// ID já existe, retornar arquivo/informações
 			if isPlaylist && !isIndexSet {
 				// Playlist completa - retornar lista organizada
-				fileList, err := utils.GetPlaylistFiles(id)
+				fileList, err := a.storage.GetPlaylistFiles(id)
 				if err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ler arquivos da playlist"})
 					return
@@ -66,12 +109,19 @@ func DownloadHandler(c *gin.Context) {
 					"id":       id,
 					"count":    len(fileList),
 					"files":    fileList,
-					"download": fmt.Sprintf("%s?id=%s&index=N", cfg.PlaylistHandler,id),
+					"download": fmt.Sprintf("%s?id=%s&index=N", a.route(cfg.PlaylistHandler), id),
+				})
+				return
+			} else if isAsync {
+				c.JSON(http.StatusOK, gin.H{
+					"status":   "Ready",
+					"id":       id,
+					"download": a.fetchURL(id),
 				})
 				return
 			} else {
 				// Download único ou item específico da playlist - retornar arquivo
-				filePath, err := utils.GetSingleFile(id)
+				filePath, err := a.storage.GetSingleFile(id)
 				if err != nil {
 					c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 					return
@@ -84,104 +134,130 @@ func DownloadHandler(c *gin.Context) {
 			}
 		}
 
-	dir := filepath.Join(cfg.DownloadDir, id)
-    os.MkdirAll(dir, os.ModePerm)
+	dir := a.storage.JobDir(id)
+
+    // O dono do job é quem pode acompanhar o progresso pelo WebSocket/SSE
+    owner, _ := a.caller(c)
+    job, err := a.jobQueue.Submit(id, jobs.Params{
+        URL:      videoURL,
+        Format:   format,
+        Quality:  quality,
+        Playlist: isPlaylist,
+        Index:    index,
+        Dir:      dir,
+    }, owner, a.maxActiveJobs(c))
+    if err != nil {
+        a.releaseDownload(c)
+    }
+    if errors.Is(err, jobs.ErrOwnerLimit) {
+        respondQuota(c, auth.ErrConcurrentLimit)
+        return
+    }
+    if errors.Is(err, jobs.ErrQueueFull) {
+        abortWithError(c, http.StatusTooManyRequests, errCodeQueueFull, "Fila de downloads cheia, tente novamente mais tarde")
+        return
+    }
+    if errors.Is(err, jobs.ErrShuttingDown) {
+        c.Header("Retry-After", "30")
+        abortWithError(c, http.StatusServiceUnavailable, errCodeShuttingDown, "Servidor encerrando, tente novamente em instantes")
+        return
+    }
 
     ///////////////Retorna imediatamente e roda em background///////////////
     if isPlaylist && !isIndexSet {
-        progressURL := fmt.Sprintf("%s?id=%s", cfg.WebSocketHandler,id)
+        progressURL := fmt.Sprintf("%s?id=%s", a.route(cfg.WebSocketHandler), id)
         c.JSON(http.StatusAccepted, gin.H{
             "id":          id,
             "progressUrl": progressURL,
+            "eventsUrl":   fmt.Sprintf("%s?id=%s", a.route(cfg.EventsHandler), id),
         })
-        go RunPlaylistDownload(videoURL, format, quality, id, dir)
         return
     }
-
-////////////// Execução normal (index ou não-playlist)////////////////////////////////////
-    outputname := cfg.OutputTemplateSingle
-
-    cmdArgs := []string{
-        "--concurrent-fragments", strconv.Itoa(cfg.ConcurrentFragments),
-        "--fragment-retries", strconv.Itoa(cfg.FragmentRetries),
-        "--retries", strconv.Itoa(cfg.Retries),
-        "--extractor-retries", strconv.Itoa(cfg.ExtractorRetries),
-        "-o", outputname,
-        "-P", dir,
+    if isAsync {
+        c.JSON(http.StatusAccepted, gin.H{
+            "id":          id,
+            "progressUrl": fmt.Sprintf("%s?id=%s", a.route(cfg.WebSocketHandler), id),
+            "eventsUrl":   fmt.Sprintf("%s?id=%s", a.route(cfg.EventsHandler), id),
+            "download":    a.fetchURL(id),
+        })
+        return
     }
 
-    formatSelector := BuildFormatSelector(format, quality)
-    cmdArgs = append(cmdArgs, "-f", formatSelector)
-
-    switch format {
-case "mp3":
-        cmdArgs = append(cmdArgs, "--extract-audio", "--audio-format", format)
-    case "mp4", "mkv", "webm":
-        cmdArgs = append(cmdArgs, "--merge-output-format", format)
+////////////// Execução normal (index ou não-playlist): aguarda o job na fila////////////
+    select {
+    case <-job.Done():
+    case <-c.Request.Context().Done():
+        return
     }
-
-    if isPlaylist {
-        if isIndexSet {
-            cmdArgs = append(cmdArgs, "--playlist-items", index)
-        }
-    } else {
-        cmdArgs = append(cmdArgs, "--no-playlist")
+    if err := job.Err(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Download failed", "details": err.Error()})
+        return
     }
 
-    cmdArgs = append(cmdArgs, videoURL)
-
-    cmd := exec.Command("yt-dlp", cmdArgs...)
-    output, err := cmd.CombinedOutput()
+    filePath, err := a.storage.GetSingleFile(id)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Download failed", "details": string(output)})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "No file found"})
         return
     }
+    safeName := utils.SanitizeFilename(filepath.Base(filePath))
+    c.FileAttachment(filePath, safeName)
+    a.storage.UpdateLastAccess(id)
+}
 
-    files, _ := os.ReadDir(dir)
-    if isPlaylist && !isIndexSet {
-        var names []string
-        for _, f := range files {
-            names = append(names, f.Name())
-        }
-        c.JSON(http.StatusOK, gin.H{
-            "id":       id,
-            "count":    len(names),
-            "files":    names,
-            "download": fmt.Sprintf("/result?id=%s&index=N", id),
-        })
-        return
+// runJob é executado pelos workers da fila para cada download
+func (a *API) runJob(job *jobs.Job) error {
+    p := job.Params
+    if err := os.MkdirAll(p.Dir, a.settings.Get().FilePermissions); err != nil {
+        return err
     }
-    
-    for _, f := range files {
-        safeName := utils.SanitizeFilename(f.Name())
-        c.FileAttachment(filepath.Join(dir, f.Name()), safeName)
-		utils.UpdateLastAccess(id)
-        return
+    var err error
+    if isFullPlaylist(job) {
+        err = a.RunPlaylistDownload(job)
+    } else {
+        err = a.runSingleDownload(job)
+    }
+    if err == nil && job.Context().Err() == nil {
+        err = a.storage.MarkComplete(job.ID)
     }
 
-    c.JSON(http.StatusInternalServerError, gin.H{"error": "No file found"})
-}
-
-func BuildFormatSelector(format string, quality string) string {
-    switch format {
-    case "mp3":
-        return "bestaudio[ext=mp3]/bestaudio"
-    case "mp4", "mkv", "webm":
-        height := ParseQuality(quality)
-        selector := fmt.Sprintf("bestvideo[height<=%d]+bestaudio/best", height)
-        selector += fmt.Sprintf("[ext=%s]", format)
-        return selector
+    switch {
+    case job.Interrupted():
+        // Servidor encerrando: o job é retomado na próxima inicialização e
+        // os WebSockets são fechados pelo Shutdown
+    case job.Context().Err() != nil:
+        // Cancelado: avisa só depois que o yt-dlp saiu, por mais que demore
+        if err := a.storage.RemovePartialFiles(job.ID); err != nil {
+            a.logger.Printf("Erro ao remover arquivos parciais de %s: %v", job.ID, err)
+        }
+        a.broadcast(a.jobMessage(job, jobs.ErrCancelled))
+        a.closeWebSocketConnections(job.ID)
     default:
-        return "best"
+        a.broadcast(a.jobMessage(job, err))
+        a.closeWebSocketConnections(job.ID)
     }
+    return err
 }
 
-func ParseQuality(quality string) int {
-    if strings.HasSuffix(quality, "p") {
-        q := strings.TrimSuffix(quality, "p")
-        if h, err := strconv.Atoi(q); err == nil {
-            return h
+func (a *API) runSingleDownload(job *jobs.Job) error {
+    if !job.Params.Playlist {
+        job.SetTotal(1)
+    }
+    publisher := a.newProgressPublisher(job)
+    return a.currentDownloader().Download(job.Context(), downloadRequest(job.Params), func(event downloader.Event) {
+        trackEvent(job, event)
+        if msg, ok := publisher.message(event); ok {
+            a.broadcast(msg)
         }
+    })
+}
+
+func downloadRequest(p jobs.Params) downloader.Request {
+    return downloader.Request{
+        URL:      p.URL,
+        Format:   p.Format,
+        Quality:  p.Quality,
+        Playlist: p.Playlist,
+        Index:    p.Index,
+        Dir:      p.Dir,
     }
-    return cfg.DefaultQualityYTDLP
-}
\ No newline at end of file
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/config"
+    "github.com/Arthur-Scaratti/yt-api/downloader"
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/utils"
+    "github.com/gin-gonic/gin"
+)
+
+// fakeDownloader grava um arquivo em req.Dir no lugar do yt-dlp
+type fakeDownloader struct {
+    mu       sync.Mutex
+    requests []downloader.Request
+    err      error
+    // Resposta de Probe; nil responde só com o título
+    info *downloader.Info
+    // Se definido, o download só termina quando ele for fechado
+    block chan struct{}
+}
+
+func (f *fakeDownloader) Download(ctx context.Context, req downloader.Request, onEvent func(downloader.Event)) error {
+    f.mu.Lock()
+    f.requests = append(f.requests, req)
+    err := f.err
+    f.mu.Unlock()
+
+    onEvent(downloader.Event{Type: downloader.EventItemStarted, Title: "Vídeo"})
+    if f.block != nil {
+        select {
+        case <-f.block:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+    if err != nil {
+        onEvent(downloader.Event{Type: downloader.EventItemFailed, Title: "Vídeo", Error: err.Error()})
+        return err
+    }
+    name := "Vídeo." + req.Format
+    if err := os.WriteFile(filepath.Join(req.Dir, name), []byte("conteúdo"), 0644); err != nil {
+        return err
+    }
+    onEvent(downloader.Event{Type: downloader.EventItemFinished, Title: "Vídeo", Filename: name})
+    return nil
+}
+
+func (f *fakeDownloader) DownloadPlaylist(ctx context.Context, req downloader.Request, onEvent func(downloader.Event)) error {
+    return f.Download(ctx, req, onEvent)
+}
+
+func (f *fakeDownloader) Probe(ctx context.Context, url string) (*downloader.Info, error) {
+    if f.info != nil {
+        return f.info, nil
+    }
+    return &downloader.Info{Title: "Vídeo"}, nil
+}
+
+func (f *fakeDownloader) calls() []downloader.Request {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]downloader.Request(nil), f.requests...)
+}
+
+// newTestAPI monta a API com o fake e DOWNLOAD_DIR temporário e devolve o
+// router com o DownloadHandler; configure ajusta a configuração antes da montagem
+func newTestAPI(t *testing.T, dl downloader.Downloader, configure ...func(*config.Config)) *gin.Engine {
+    t.Helper()
+    api := newTestHandlers(t, dl, configure...)
+
+    r := gin.New()
+    r.GET(api.settings.Get().DownloadHandler, api.DownloadHandler)
+    return r
+}
+
+// newTestHandlers é newTestAPI sem o router, para testes que registram as
+// próprias rotas
+func newTestHandlers(t *testing.T, dl downloader.Downloader, configure ...func(*config.Config)) *API {
+    t.Helper()
+    gin.SetMode(gin.TestMode)
+
+    cfg := config.Default()
+    cfg.DownloadDir = t.TempDir()
+    cfg.URLAllowedHosts = []string{"youtube.com"}
+    cfg.URLAllowPrivate = true
+    for _, fn := range configure {
+        fn(cfg)
+    }
+    settings := config.NewHolder(cfg, nil)
+    logger := log.New(io.Discard, "", 0)
+
+    api := New(settings, utils.NewStorage(settings, logger), nil, logger)
+    api.SetDownloader(dl)
+    t.Cleanup(func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        api.Shutdown(ctx)
+    })
+    return api
+}
+
+func get(r http.Handler, path string, query url.Values) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil)
+    r.ServeHTTP(w, req)
+    return w
+}
+
+func TestDownloadHandlerRejectsInvalidParams(t *testing.T) {
+    tests := []struct {
+        name  string
+        query url.Values
+        code  string
+    }{
+        {"url fora da política", url.Values{"url": {"https://vimeo.com/1"}}, errCodeInvalidURL},
+        {"url como opção", url.Values{"url": {"--exec=id"}}, errCodeInvalidURL},
+        {"format inválido", url.Values{"url": {"https://youtube.com/watch?v=x"}, "format": {"flac"}}, errCodeInvalidParam},
+        {"quality inválida", url.Values{"url": {"https://youtube.com/watch?v=x"}, "quality": {"999p"}}, errCodeInvalidParam},
+        {"playlist inválido", url.Values{"url": {"https://youtube.com/playlist?list=x"}, "playlist": {"sim"}}, errCodeInvalidParam},
+        {"index inválido", url.Values{"url": {"https://youtube.com/playlist?list=x"}, "playlist": {"true"}, "index": {"1;rm"}}, errCodeInvalidParam},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fake := &fakeDownloader{}
+            w := get(newTestAPI(t, fake), "/download", tt.query)
+
+            if w.Code != http.StatusBadRequest {
+                t.Fatalf("status = %d, esperado 400: %s", w.Code, w.Body)
+            }
+            var body struct{ Code string }
+            if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != tt.code {
+                t.Fatalf("code = %q (%v), esperado %q", body.Code, err, tt.code)
+            }
+            if calls := fake.calls(); len(calls) != 0 {
+                t.Fatalf("downloader chamado com parâmetros inválidos: %+v", calls)
+            }
+        })
+    }
+}
+
+func TestDownloadHandlerServesFileAndCaches(t *testing.T) {
+    fake := &fakeDownloader{}
+    r := newTestAPI(t, fake)
+
+    w := get(r, "/download", url.Values{"url": {"https://youtube.com/watch?v=x"}, "format": {"MP4"}, "quality": {"720"}})
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body)
+    }
+    if w.Body.String() != "conteúdo" {
+        t.Fatalf("corpo = %q, esperado o arquivo baixado", w.Body)
+    }
+    calls := fake.calls()
+    if len(calls) != 1 {
+        t.Fatalf("downloader chamado %d vezes, esperado 1", len(calls))
+    }
+    if calls[0].Format != "mp4" || calls[0].Quality != "720p" || calls[0].Playlist {
+        t.Fatalf("requisição ao downloader sem normalizar: %+v", calls[0])
+    }
+
+    // Os mesmos parâmetros escritos de outra forma caem no mesmo id e vêm do cache
+    w = get(r, "/download", url.Values{"url": {"https://youtube.com/watch?v=x"}, "format": {"mp4"}, "quality": {"720p"}})
+    if w.Code != http.StatusOK || w.Body.String() != "conteúdo" {
+        t.Fatalf("segunda requisição: status %d, corpo %q", w.Code, w.Body)
+    }
+    if calls := fake.calls(); len(calls) != 1 {
+        t.Fatalf("download repetido em vez de usar o cache: %d chamadas", len(calls))
+    }
+}
+
+func TestDownloadHandlerIgnoresQualityForAudio(t *testing.T) {
+    fake := &fakeDownloader{}
+    r := newTestAPI(t, fake)
+
+    for _, quality := range []string{"720p", "1080", ""} {
+        query := url.Values{"url": {"https://youtube.com/watch?v=x"}, "format": {"mp3"}}
+        if quality != "" {
+            query.Set("quality", quality)
+        }
+        if w := get(r, "/download", query); w.Code != http.StatusOK {
+            t.Fatalf("quality %q: status = %d, esperado 200: %s", quality, w.Code, w.Body)
+        }
+    }
+    // A quality não entra no id do mp3, então só o primeiro pedido baixa
+    if calls := fake.calls(); len(calls) != 1 || calls[0].Quality != "" {
+        t.Fatalf("downloader chamado %d vezes (%+v), esperado 1 sem quality", len(calls), calls)
+    }
+
+    // Quality inválida continua recusada
+    w := get(r, "/download", url.Values{"url": {"https://youtube.com/watch?v=x"}, "format": {"mp3"}, "quality": {"999p"}})
+    if w.Code != http.StatusBadRequest {
+        t.Fatalf("quality inválida com mp3: status = %d, esperado 400", w.Code)
+    }
+}
+
+func TestDownloadHandlerAsync(t *testing.T) {
+    fake := &fakeDownloader{}
+    w := get(newTestAPI(t, fake), "/download", url.Values{"url": {"https://youtube.com/watch?v=x"}, "async": {"true"}})
+
+    if w.Code != http.StatusAccepted {
+        t.Fatalf("status = %d, esperado 202: %s", w.Code, w.Body)
+    }
+    var body struct {
+        ID          string `json:"id"`
+        ProgressURL string `json:"progressUrl"`
+        EventsURL   string `json:"eventsUrl"`
+        Download    string `json:"download"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+        t.Fatal(err)
+    }
+    if body.ID == "" || body.EventsURL != "/events?id="+body.ID || body.Download != "/fetch?id="+body.ID {
+        t.Fatalf("resposta incompleta: %+v", body)
+    }
+}
+
+func TestDownloadHandlerReportsFailure(t *testing.T) {
+    fake := &fakeDownloader{err: errors.New("ERROR: Video unavailable")}
+    w := get(newTestAPI(t, fake), "/download", url.Values{"url": {"https://youtube.com/watch?v=x"}})
+
+    if w.Code != http.StatusInternalServerError {
+        t.Fatalf("status = %d, esperado 500: %s", w.Code, w.Body)
+    }
+    var body struct{ Details string }
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Details != "ERROR: Video unavailable" {
+        t.Fatalf("details = %q (%v), esperado o erro do downloader", body.Details, err)
+    }
+}
+
+func TestDownloadHandlerConcurrentLimit(t *testing.T) {
+    fake := &fakeDownloader{block: make(chan struct{})}
+    defer close(fake.block)
+    r := newTestAPI(t, fake, func(cfg *config.Config) {
+        cfg.APIKeys = []string{"alice:token-da-alice"}
+        cfg.APIKeyMaxJobs = 2
+        cfg.JobMaxQueue = 20
+    })
+
+    // Requisições simultâneas com URLs diferentes não podem passar do limite
+    const requests = 10
+    var mu sync.Mutex
+    var accepted []string
+    counts := map[int]int{}
+    var wg sync.WaitGroup
+    for i := 0; i < requests; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            videoURL := fmt.Sprintf("https://youtube.com/watch?v=%d", i)
+            w := get(r, "/download", url.Values{"url": {videoURL}, "async": {"true"}, "token": {"token-da-alice"}})
+            mu.Lock()
+            defer mu.Unlock()
+            counts[w.Code]++
+            if w.Code == http.StatusAccepted {
+                accepted = append(accepted, videoURL)
+            }
+        }(i)
+    }
+    wg.Wait()
+
+    if counts[http.StatusAccepted] != 2 || counts[http.StatusTooManyRequests] != requests-2 {
+        t.Fatalf("respostas = %v, esperado 2 aceitas e %d recusadas", counts, requests-2)
+    }
+
+    // Pedir de novo um job que já é da chave não conta no limite
+    w := get(r, "/download", url.Values{"url": {accepted[0]}, "async": {"true"}, "token": {"token-da-alice"}})
+    if w.Code != http.StatusAccepted {
+        t.Fatalf("job da própria chave: status = %d, esperado 202: %s", w.Code, w.Body)
+    }
+}
+
+func TestDownloadHandlerDailyLimit(t *testing.T) {
+    fake := &fakeDownloader{block: make(chan struct{})}
+    defer close(fake.block)
+    r := newTestAPI(t, fake, func(cfg *config.Config) {
+        cfg.APIKeys = []string{"alice:token-da-alice"}
+        cfg.APIKeyMaxJobs = 1
+        cfg.APIKeyMaxDaily = 3
+        cfg.JobMaxQueue = 20
+    })
+    request := func(i int) int {
+        videoURL := fmt.Sprintf("https://youtube.com/watch?v=%d", i)
+        return get(r, "/download", url.Values{"url": {videoURL}, "async": {"true"}, "token": {"token-da-alice"}}).Code
+    }
+
+    // Recusas pelo limite de jobs simultâneos devolvem a vaga da cota diária
+    if code := request(0); code != http.StatusAccepted {
+        t.Fatalf("primeira requisição: status = %d, esperado 202", code)
+    }
+    for i := 1; i <= 5; i++ {
+        if code := request(i); code != http.StatusTooManyRequests {
+            t.Fatalf("requisição %d: status = %d, esperado 429", i, code)
+        }
+    }
+
+    // Requisições simultâneas não passam das vagas que sobraram
+    const requests = 10
+    var mu sync.Mutex
+    counts := map[int]int{}
+    var wg sync.WaitGroup
+    for i := 0; i < requests; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            // Mesmo job da primeira requisição: não conta no limite simultâneo
+            code := get(r, "/download", url.Values{"url": {"https://youtube.com/watch?v=0"}, "async": {"true"}, "token": {"token-da-alice"}}).Code
+            mu.Lock()
+            counts[code]++
+            mu.Unlock()
+        }()
+    }
+    wg.Wait()
+    if counts[http.StatusAccepted] != 2 || counts[http.StatusTooManyRequests] != requests-2 {
+        t.Fatalf("respostas = %v, esperado 2 aceitas e %d recusadas", counts, requests-2)
+    }
+}
+
+func TestDownloadHandlerPlaylistTotal(t *testing.T) {
+    fake := &fakeDownloader{
+        block: make(chan struct{}),
+        info:  &downloader.Info{Title: "Playlist", IsPlaylist: true, Count: 3},
+    }
+    defer close(fake.block)
+    api := newTestHandlers(t, fake)
+    r := gin.New()
+    r.GET("/download", api.DownloadHandler)
+
+    w := get(r, "/download", url.Values{"url": {"https://youtube.com/playlist?list=x"}, "playlist": {"true"}})
+    if w.Code != http.StatusAccepted {
+        t.Fatalf("status = %d, esperado 202: %s", w.Code, w.Body)
+    }
+    var body struct{ ID jobid.ID }
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+        t.Fatal(err)
+    }
+
+    // O total vem do Probe antes de o download terminar o primeiro item
+    for deadline := time.Now().Add(5 * time.Second); ; {
+        job, ok := api.jobQueue.Get(body.ID)
+        if ok && job.Status().Total == 3 {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("total da playlist não registrado no job")
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestDownloadHandlerJobDirPermissions(t *testing.T) {
+    fake := &fakeDownloader{}
+    api := newTestHandlers(t, fake, func(cfg *config.Config) {
+        cfg.FilePermissions = 0700
+    })
+    r := gin.New()
+    r.GET("/download", api.DownloadHandler)
+
+    if w := get(r, "/download", url.Values{"url": {"https://youtube.com/watch?v=x"}}); w.Code != http.StatusOK {
+        t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body)
+    }
+    info, err := os.Stat(fake.calls()[0].Dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if perm := info.Mode().Perm(); perm != 0700 {
+        t.Fatalf("permissão do diretório do download = %#o, esperado 0700", perm)
+    }
+}
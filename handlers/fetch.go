@@ -0,0 +1,53 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "path/filepath"
+
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
+    "github.com/Arthur-Scaratti/yt-api/utils"
+    "github.com/gin-gonic/gin"
+)
+
+// FetchHandler entrega o arquivo de um download único feito com async=true
+func (a *API) FetchHandler(c *gin.Context) {
+    id, ok := parseJobID(c, c.Query("id"))
+    if !ok {
+        return
+    }
+
+    if a.storage.CheckExistingID(id) {
+        filePath, err := a.storage.GetSingleFile(id)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+            return
+        }
+        safeName := utils.SanitizeFilename(filepath.Base(filePath))
+        c.FileAttachment(filePath, safeName)
+        return
+    }
+
+    job, found := a.jobQueue.Get(id)
+    if !found {
+        c.JSON(http.StatusNotFound, gin.H{"error": "ID inválido ou nenhum arquivo encontrado"})
+        return
+    }
+
+    status := job.Status()
+    switch status.State {
+    case jobs.StateQueued, jobs.StateRunning:
+        // Ainda em andamento: o cliente deve acompanhar o progresso e tentar de novo
+        c.JSON(http.StatusAccepted, gin.H{"status": status.State, "job": status})
+    case jobs.StateCancelled:
+        c.JSON(http.StatusGone, gin.H{"error": "Download cancelado", "job": status})
+    default:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Download failed", "details": status.Error})
+    }
+}
+
+// fetchURL é a URL do FetchHandler para o id
+func (a *API) fetchURL(id jobid.ID) string {
+    return fmt.Sprintf("%s?id=%s", a.route(a.settings.Get().FetchHandler), id)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
+    "github.com/gin-gonic/gin"
+)
+
+// Tempo máximo para o yt-dlp encerrar depois do cancelamento
+const cancelTimeout = 10 * time.Second
+
+// JobStatusHandler retorna o estado de um job registrado na fila
+func (a *API) JobStatusHandler(c *gin.Context) {
+    id, ok := parseJobID(c, c.Param("id"))
+    if !ok {
+        return
+    }
+    job, ok := a.jobQueue.Get(id)
+    if !ok {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Job não encontrado"})
+        return
+    }
+    if !a.authorizeJob(c, job) {
+        return
+    }
+    c.JSON(http.StatusOK, job.Status())
+}
+
+// CancelJobHandler cancela um job da fila ou em execução
+func (a *API) CancelJobHandler(c *gin.Context) {
+    id, ok := parseJobID(c, c.Param("id"))
+    if !ok {
+        return
+    }
+    if job, ok := a.jobQueue.Get(id); ok && !a.authorizeJob(c, job) {
+        return
+    }
+
+    job, err := a.cancelJob(id)
+    switch {
+    case errors.Is(err, jobs.ErrNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Job não encontrado"})
+    case errors.Is(err, jobs.ErrFinished):
+        c.JSON(http.StatusConflict, gin.H{"error": "Job já terminou", "job": job.Status()})
+    case errors.Is(err, errCancelPending):
+        c.JSON(http.StatusAccepted, job.Status())
+    case err != nil:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusOK, job.Status())
+    }
+}
+
+// authorizeJob responde 401/403 se a requisição não é de um dono do job
+func (a *API) authorizeJob(c *gin.Context, job *jobs.Job) bool {
+    owner, ok := a.caller(c)
+    if !ok {
+        respondUnauthorized(c)
+        return false
+    }
+    if !a.canAccessJob(owner, job) {
+        respondForbidden(c)
+        return false
+    }
+    return true
+}
+
+// errCancelPending indica que o yt-dlp ainda não saiu depois de cancelTimeout;
+// o job_cancelled é publicado por runJob quando ele sair
+var errCancelPending = errors.New("cancelamento em andamento, aguardando o fim do yt-dlp")
+
+// cancelJob cancela o job e espera o yt-dlp sair. Jobs em execução são
+// finalizados por runJob (arquivos parciais e job_cancelled); um job ainda
+// na fila nunca chega lá, então é avisado aqui
+func (a *API) cancelJob(id jobid.ID) (*jobs.Job, error) {
+    job, err := a.jobQueue.Cancel(id)
+    if err != nil {
+        return job, err
+    }
+
+    if job.Status().StartedAt == nil {
+        a.broadcast(a.jobMessage(job, jobs.ErrCancelled))
+        a.closeWebSocketConnections(id)
+        return job, nil
+    }
+
+    select {
+    case <-job.Done():
+        return job, nil
+    case <-time.After(cancelTimeout):
+        return job, errCancelPending
+    }
+}
@@ -2,33 +2,43 @@ package handlers
 
 import (
 	"archive/zip"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"github.com/Arthur-Scaratti/yt-api/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func PlaylistHandler(c *gin.Context) {
-    id := c.Query("id")
+func (a *API) PlaylistHandler(c *gin.Context) {
+    id, ok := parseJobID(c, c.Query("id"))
+    if !ok {
+        return
+    }
     index := c.Query("index")
     zipRequested := index == "" || strings.ToUpper(index) == "N"
-    dir := filepath.Join(cfg.DownloadDir, id)
+    dir := a.storage.JobDir(id)
     
     files, err := os.ReadDir(dir)
     if err != nil || len(files) == 0 {
         c.JSON(http.StatusNotFound, gin.H{"error": "ID inválido ou nenhum arquivo encontrado"})
         return
     }
-	utils.UpdateLastAccess(id)
+	a.storage.UpdateLastAccess(id)
     if !zipRequested {
-        prefix := fmt.Sprintf("%s -", index)
+        wanted, err := strconv.Atoi(index)
+        if err != nil || wanted < 1 {
+            abortWithError(c, http.StatusBadRequest, errCodeInvalidParam, "index deve ser um número a partir de 1 ou N")
+            return
+        }
         var matched os.DirEntry
         for _, file := range files {
-            if strings.HasPrefix(file.Name(), prefix) {
+            if !utils.IsMediaFile(file.Name()) {
+                continue
+            }
+            if n, ok := utils.PlaylistIndex(file.Name()); ok && n == wanted {
                 matched = file
                 break
             }
@@ -43,6 +53,11 @@ func PlaylistHandler(c *gin.Context) {
         return
     }
     
+    // O ZIP só é gerado com a playlist completa, senão ficaria em cache incompleto
+    if !a.storage.IsComplete(id) {
+        c.JSON(http.StatusConflict, gin.H{"error": "Playlist ainda em andamento"})
+        return
+    }
     zipPath := filepath.Join(dir, "playlist.zip")
     if _, err := os.Stat(zipPath); os.IsNotExist(err) {
         err := createZip(zipPath, dir, files)
@@ -66,7 +81,7 @@ func createZip(zipPath string, dir string, files []os.DirEntry) error {
 		defer zipWriter.Close()
 	
 		for _, file := range files {
-			if file.Name() == "playlist.zip" {
+			if !utils.IsMediaFile(file.Name()) {
 				continue
 			}
 			filePath := filepath.Join(dir, file.Name())
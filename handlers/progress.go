@@ -0,0 +1,145 @@
+package handlers
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/downloader"
+    "github.com/Arthur-Scaratti/yt-api/events"
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
+)
+
+// Intervalo mínimo entre mensagens item_progress do mesmo item
+const progressInterval = time.Second
+
+// trackEvent reflete um evento de progresso no status do job
+func trackEvent(job *jobs.Job, event downloader.Event) {
+    switch event.Type {
+    case downloader.EventItemStarted:
+        if event.Total > 0 {
+            job.SetTotal(event.Total)
+        }
+        job.SetProgress(jobs.Progress{Index: event.Index, Title: event.Title})
+    case downloader.EventItemProgress:
+        job.SetProgress(jobs.Progress{
+            Index:   event.Index,
+            Title:   event.Title,
+            Percent: eventPercent(event),
+            Speed:   event.Speed,
+            ETA:     event.ETA,
+        })
+    case downloader.EventItemPostProcess:
+        job.SetProgress(jobs.Progress{
+            Index:   event.Index,
+            Title:   event.Title,
+            Percent: 100,
+            Stage:   event.Stage,
+        })
+    case downloader.EventItemFinished:
+        job.AddItem(jobs.Item{Index: event.Index, Title: event.Title, Filename: event.Filename})
+    case downloader.EventItemFailed:
+        job.AddItem(jobs.Item{Index: event.Index, Title: event.Title, Error: event.Error})
+    }
+}
+
+// progressPublisher converte os eventos do downloader em mensagens para os
+// clientes, limitando a frequência de item_progress
+type progressPublisher struct {
+    api   *API
+    job   *jobs.Job
+    id    jobid.ID
+    index int
+    stage string
+    sent  time.Time
+}
+
+func (a *API) newProgressPublisher(job *jobs.Job) *progressPublisher {
+    return &progressPublisher{api: a, job: job, id: job.ID}
+}
+
+// message retorna a mensagem do evento, ou false se ela deve ser omitida
+func (p *progressPublisher) message(event downloader.Event) (events.Message, bool) {
+    var msg events.Message
+    switch event.Type {
+    case downloader.EventItemProgress, downloader.EventItemPostProcess:
+        percent := eventPercent(event)
+        if event.Type == downloader.EventItemPostProcess {
+            percent = 100
+        }
+        // Sempre envia a troca de item ou de etapa; o resto é limitado por tempo
+        changed := event.Index != p.index || event.Stage != p.stage
+        if !changed && percent < 100 && time.Since(p.sent) < progressInterval {
+            return msg, false
+        }
+        p.index, p.stage, p.sent = event.Index, event.Stage, time.Now()
+
+        msg = events.New(events.ItemProgress, p.id)
+        msg.Progress = &events.Progress{
+            Percent: percent,
+            Speed:   event.Speed,
+            ETA:     event.ETA,
+            Stage:   event.Stage,
+        }
+    case downloader.EventItemFinished:
+        msg = events.New(events.ItemDone, p.id)
+        msg.Filename = event.Filename
+        if !isFullPlaylist(p.job) {
+            msg.Download = p.api.fetchURL(p.id)
+        } else if event.Index > 0 {
+            msg.Download = fmt.Sprintf("%s?id=%s&index=%d", p.api.route(p.api.settings.Get().PlaylistHandler), p.id, event.Index)
+        }
+    case downloader.EventItemFailed:
+        msg = events.New(events.ItemError, p.id)
+        msg.Error = event.Error
+    default:
+        return msg, false
+    }
+
+    msg.Index = event.Index
+    msg.Total = event.Total
+    msg.Title = event.Title
+    return msg, true
+}
+
+// jobMessage monta a mensagem final do job a partir do resultado do download
+func (a *API) jobMessage(job *jobs.Job, err error) events.Message {
+    var msg events.Message
+    switch {
+    case job.Context().Err() != nil:
+        msg = events.New(events.JobCancelled, job.ID)
+    case err != nil:
+        msg = events.New(events.JobFailed, job.ID)
+        msg.Error = err.Error()
+    default:
+        msg = events.New(events.JobDone, job.ID)
+        if isFullPlaylist(job) {
+            msg.Download = fmt.Sprintf("%s?id=%s", a.route(a.settings.Get().PlaylistHandler), job.ID)
+        } else {
+            msg.Download = a.fetchURL(job.ID)
+        }
+    }
+    msg.Total = job.Status().Total
+    return msg
+}
+
+// snapshotMessage monta a mensagem com o estado atual do job
+func snapshotMessage(job *jobs.Job) events.Message {
+    msg := events.New(events.Snapshot, job.ID)
+    status := job.Status()
+    msg.Status = &status
+    return msg
+}
+
+// isFullPlaylist indica se o job baixa a playlist inteira (sem index)
+func isFullPlaylist(job *jobs.Job) bool {
+    return job.Params.Playlist && job.Params.Index == ""
+}
+
+func eventPercent(event downloader.Event) float64 {
+    percent := event.Percent()
+    if percent < 0 {
+        return 0
+    }
+    return percent
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+    "fmt"
+    "math"
+    "net/http"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/ratelimit"
+    "github.com/gin-gonic/gin"
+)
+
+const errCodeRateLimited = "rate_limited"
+
+// RateLimitMiddleware limita as requisições de cada cliente na rota com o
+// token bucket informado. O cliente é a chave de API, se houver, ou o IP.
+// Com limiter nil ou desligado a rota fica sem limite
+func (a *API) RateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !limiter.Enabled() {
+            c.Next()
+            return
+        }
+
+        client := "ip:" + c.ClientIP()
+        if key, ok := a.apiKey(c); ok {
+            client = "key:" + key.Name
+        }
+
+        result := limiter.Allow(client)
+        c.Header("X-RateLimit-Limit", fmt.Sprint(result.Limit))
+        c.Header("X-RateLimit-Remaining", fmt.Sprint(result.Remaining))
+        c.Header("X-RateLimit-Reset", fmt.Sprint(seconds(result.Reset)))
+
+        if !result.Allowed {
+            c.Header("Retry-After", fmt.Sprint(seconds(result.RetryAfter)))
+            abortWithError(c, http.StatusTooManyRequests, errCodeRateLimited, "Muitas requisições, tente novamente mais tarde")
+            return
+        }
+        c.Next()
+    }
+}
+
+// seconds arredonda para cima, como esperado em Retry-After
+func seconds(d time.Duration) int {
+    return int(math.Ceil(d.Seconds()))
+}
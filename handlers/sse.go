@@ -0,0 +1,118 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/events"
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    // Intervalo dos comentários enviados para manter a conexão SSE aberta em proxies
+    sseKeepAlive = 15 * time.Second
+    // Mensagens novas que cabem no buffer além do replay do log
+    sseLiveBuffer = 256
+)
+
+// EventsHandler transmite as mensagens de progresso de um job via
+// Server-Sent Events, alternativa ao WebSocket para clientes atrás de proxies
+func (a *API) EventsHandler(c *gin.Context) {
+    if c.Query("id") == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Missing id"})
+        return
+    }
+    id, ok := parseJobID(c, c.Query("id"))
+    if !ok {
+        return
+    }
+    owner, ok := a.caller(c)
+    if !ok {
+        respondUnauthorized(c)
+        return
+    }
+    if !a.canAccess(owner, id) {
+        respondForbidden(c)
+        return
+    }
+
+    // Last-Event-ID é enviado pelo navegador ao reconectar
+    lastID := c.GetHeader("Last-Event-ID")
+    if lastID == "" {
+        lastID = c.Query("since")
+    }
+    since, _ := strconv.ParseInt(lastID, 10, 64)
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
+    c.Status(http.StatusOK)
+
+    if job, ok := a.jobQueue.Get(id); ok {
+        writeSSE(c, snapshotMessage(job))
+    }
+
+    // O hub não pode bloquear: se o cliente não acompanha, a conexão é
+    // encerrada e ele retoma pelo Last-Event-ID. O buffer comporta o log
+    // inteiro do job, que o Subscribe entrega de uma vez
+    messages := make(chan events.Message, a.hub.MaxLog()+sseLiveBuffer)
+    overflow := make(chan struct{})
+    overflowed := false
+    unsubscribe, closed := a.hub.Subscribe(id, since, func(msg events.Message) {
+        if overflowed {
+            return
+        }
+        select {
+        case messages <- msg:
+        default:
+            overflowed = true
+            close(overflow)
+        }
+    })
+    defer unsubscribe()
+
+    if closed {
+        // Job já encerrado: o log inteiro já está no canal
+        for len(messages) > 0 {
+            writeSSE(c, <-messages)
+        }
+        return
+    }
+
+    keepAlive := time.NewTicker(sseKeepAlive)
+    defer keepAlive.Stop()
+
+    for {
+        select {
+        case msg := <-messages:
+            writeSSE(c, msg)
+            if msg.Type.Final() {
+                return
+            }
+        case <-keepAlive.C:
+            fmt.Fprint(c.Writer, ": ping\n\n")
+            c.Writer.Flush()
+        case <-overflow:
+            return
+        case <-c.Request.Context().Done():
+            return
+        }
+    }
+}
+
+// writeSSE escreve a mensagem no formato text/event-stream
+func writeSSE(c *gin.Context, msg events.Message) {
+    data, err := json.Marshal(msg)
+    if err != nil {
+        return
+    }
+    if msg.Seq > 0 {
+        fmt.Fprintf(c.Writer, "id: %d\n", msg.Seq)
+    }
+    fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", msg.Type, data)
+    c.Writer.Flush()
+}
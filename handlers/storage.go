@@ -0,0 +1,35 @@
+package handlers
+
+import (
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+// Storage guarda os downloads de cada ID. utils.Storage é a implementação
+// padrão, em diretórios dentro de DOWNLOAD_DIR; outra implementação pode ser
+// passada com ytapi.WithStorage. O downloader sempre grava em JobDir, então
+// ele precisa ser um caminho local
+type Storage interface {
+    // EnsureDir cria o diretório base dos downloads
+    EnsureDir() error
+    // JobDir é o diretório onde o downloader grava os arquivos do ID
+    JobDir(id jobid.ID) string
+    // CheckExistingID indica se o ID já foi baixado e registra o acesso
+    CheckExistingID(id jobid.ID) bool
+    // IsComplete indica se o download do ID foi concluído
+    IsComplete(id jobid.ID) bool
+    // MarkComplete registra que o download do ID terminou com sucesso
+    MarkComplete(id jobid.ID) error
+    // GetPlaylistFiles lista os itens baixados de uma playlist
+    GetPlaylistFiles(id jobid.ID) ([]map[string]string, error)
+    // GetSingleFile retorna o caminho do arquivo de um download único
+    GetSingleFile(id jobid.ID) (string, error)
+    // UpdateLastAccess registra um acesso ao ID, usado pelo cleanup
+    UpdateLastAccess(id jobid.ID)
+    // RemovePartialFiles apaga os arquivos parciais de um job cancelado
+    RemovePartialFiles(id jobid.ID) error
+
+    // Cleanup automático, iniciado pelo Start do servidor e parado no Shutdown
+    RunSimpleCleanup()
+    StartAutoCleanup()
+    StopAutoCleanup()
+}
@@ -1,86 +1,233 @@
 package handlers
 
 import (
-    "log"
+    "encoding/json"
+    "errors"
     "net/http"
-    "sync"
+    "strconv"
 
+    "github.com/Arthur-Scaratti/yt-api/auth"
+    "github.com/Arthur-Scaratti/yt-api/events"
+    "github.com/Arthur-Scaratti/yt-api/jobid"
     "github.com/gin-gonic/gin"
     "github.com/gorilla/websocket"
-    "slices"
 )
 
-var upgrader = websocket.Upgrader{
-    CheckOrigin: func(r *http.Request) bool {
-        return true
-    },
+// Assinatura de todos os jobs no modo multiplexado
+const wsWildcard = "*"
+
+// Motivo do close frame 1013 enviado a quem estoura a fila de envio
+const wsSlowConsumerReason = "fila de envio cheia; reconecte com since"
+
+// wsRequest é uma mensagem de controle enviada pelo cliente
+type wsRequest struct {
+    Type string `json:"type"`
+    // Job alvo de cancel; no modo ?id= o padrão é o job da conexão
+    ID string `json:"id"`
+    // Jobs de subscribe/unsubscribe; "*" assina todos
+    IDs []string `json:"ids"`
+    // Seq da última mensagem recebida por job, para o replay de subscribe
+    Since map[string]int64 `json:"since"`
 }
-var (
-    wsClients = make(map[string][]*websocket.Conn)
-    wsMutex   sync.RWMutex
-)
 
-func WebSocketHandler(c *gin.Context) {
-    id := c.Query("id")
-    if id == "" {
+// WebSocketHandler atende dois modos: com ?id= acompanha um job e fecha
+// quando ele termina; sem id a conexão é multiplexada e o cliente escolhe
+// os jobs com mensagens subscribe/unsubscribe
+func (a *API) WebSocketHandler(c *gin.Context) {
+    var id jobid.ID
+    if raw := c.Query("id"); raw != "" {
+        var ok bool
+        if id, ok = parseJobID(c, raw); !ok {
+            return
+        }
+    }
+    // Seq da última mensagem já recebida pelo cliente; 0 recebe todo o log
+    since, _ := strconv.ParseInt(c.Query("since"), 10, 64)
+
+    // Autenticação e posse do job são checadas antes do upgrade para
+    // responder com o status HTTP correto
+    owner, ok := a.caller(c)
+    if !ok {
+        respondUnauthorized(c)
         return
     }
+    if id != "" && !a.canAccess(owner, id) {
+        respondForbidden(c)
+        return
+    }
+
+    // O navegador exige que o servidor confirme o subprotocolo usado para
+    // enviar o token
+    var header http.Header
+    for _, protocol := range websocket.Subprotocols(c.Request) {
+        if protocol == auth.BearerProtocol {
+            header = http.Header{"Sec-Websocket-Protocol": {auth.BearerProtocol}}
+            break
+        }
+    }
 
-    conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+    conn, err := a.upgrader.Upgrade(c.Writer, c.Request, header)
     if err != nil {
-        log.Printf("Erro no upgrade do websocket: %v", err)
+        a.logger.Printf("Erro no upgrade do websocket: %v", err)
         return
     }
-    wsMutex.Lock()
-    wsClients[id] = append(wsClients[id], conn)
-    wsMutex.Unlock()
+    client := newWSClient(conn, id, owner, a.wsSendBuffer(), a.logger)
+    go client.writeLoop()
 
     defer func() {
-        wsMutex.Lock()
-        defer wsMutex.Unlock()
-
-        connections := wsClients[id]
-        for i, c := range connections {
-            if c == conn {
-                wsClients[id] = slices.Delete(connections, i, i+1)
-                break
+        client.unsubscribeAll()
+        a.wsConnections.remove(client)
+        client.close()
+        a.logger.Printf("Cliente WebSocket desconectado (id: %q)", id)
+    }()
+
+    if !a.wsConnections.add(client) {
+        // Servidor encerrando
+        return
+    }
+    if id != "" {
+        if closed := a.subscribeJob(client, id, since); closed {
+            // Job já encerrado: o log completo já está na fila de envio
+            return
+        }
+    }
+
+    client.readLoop(func(data []byte) {
+        var req wsRequest
+        if err := json.Unmarshal(data, &req); err != nil {
+            sendWSError(client, "Mensagem inválida")
+            return
+        }
+        a.handleWSRequest(client, req)
+    })
+}
+
+func (a *API) handleWSRequest(client *wsClient, req wsRequest) {
+    switch req.Type {
+    case "subscribe":
+        var ids []string
+        for _, raw := range req.IDs {
+            if raw == wsWildcard {
+                if a.subscribeAll(client) {
+                    ids = append(ids, raw)
+                }
+                continue
+            }
+            id, err := jobid.Parse(raw)
+            if err != nil {
+                sendWSError(client, err.Error())
+                continue
+            }
+            if !a.canAccess(client.owner, id) {
+                sendWSError(client, "Job não pertence a este token: "+raw)
+                continue
             }
+            ids = append(ids, raw)
+            a.subscribeJob(client, id, req.Since[raw])
         }
-        if len(wsClients[id]) == 0 {
-            delete(wsClients, id)
+        reply := events.New(events.Subscribed, "")
+        reply.IDs = ids
+        client.enqueue(reply)
+    case "unsubscribe":
+        var ids []string
+        for _, id := range req.IDs {
+            if client.unsubscribe(id) {
+                ids = append(ids, id)
+            }
         }
-        conn.Close()
-        log.Printf("Cliente desconectado e removido para o id: %s", id)
-    }()
-    for {
-        if _, _, err := conn.NextReader(); err != nil {
-            break
+        reply := events.New(events.Unsubscribed, "")
+        reply.IDs = ids
+        client.enqueue(reply)
+    case "cancel":
+        id := client.jobID
+        if req.ID != "" {
+            var err error
+            if id, err = jobid.Parse(req.ID); err != nil {
+                sendWSError(client, err.Error())
+                return
+            }
+        }
+        if id == "" {
+            sendWSError(client, "cancel precisa de id")
+            return
+        }
+        if !a.canAccess(client.owner, id) {
+            sendWSError(client, "Job não pertence a este token: "+id.String())
+            return
         }
+        go func() {
+            if _, err := a.cancelJob(id); err != nil && !errors.Is(err, errCancelPending) {
+                a.logger.Printf("Erro ao cancelar o job %s: %v", id, err)
+            }
+        }()
+    default:
+        sendWSError(client, "Tipo de mensagem desconhecido: "+req.Type)
     }
 }
 
-func broadcastItem(id, title string) {
-    wsMutex.RLock()
-    connections := wsClients[id]
-    wsMutex.RUnlock()
-    
-    for _, conn := range connections {
-        conn.WriteJSON(gin.H{
-            "id":    id,
-            "title": title,
-        })
+// subscribeJob envia o estado atual do job, as mensagens com seq maior que
+// since e passa a encaminhar as novas. Retorna se o job já foi encerrado.
+// Antes do replay espera a fila de envio ter espaço para o log inteiro, então
+// um subscribe com muitos ids é entregue no ritmo do cliente
+func (a *API) subscribeJob(client *wsClient, id jobid.ID, since int64) bool {
+    if !client.waitRoom(a.hub.MaxLog() + 1) {
+        return true
     }
+    closed := false
+    client.subscribe(id.String(), func() func() {
+        if job, ok := a.jobQueue.Get(id); ok {
+            client.enqueue(snapshotMessage(job))
+        }
+
+        var unsubscribe func()
+        unsubscribe, closed = a.hub.Subscribe(id, since, func(msg events.Message) {
+            client.enqueue(msg)
+        })
+        return unsubscribe
+    })
+    return closed
 }
 
-func closeWebSocketConnections(id string) {
-    wsMutex.Lock()
-    defer wsMutex.Unlock()
-    
-    connections := wsClients[id]
-    for _, conn := range connections {
-        conn.Close()
+// subscribeAll encaminha as mensagens de todos os jobs, começando pelo
+// estado dos jobs ativos. Jobs com assinatura própria não são repetidos e,
+// com autenticação ligada, só chegam os jobs do dono da conexão
+func (a *API) subscribeAll(client *wsClient) bool {
+    return client.subscribe(wsWildcard, func() func() {
+        for _, job := range a.jobQueue.Active() {
+            if a.canAccessJob(client.owner, job) {
+                client.enqueue(snapshotMessage(job))
+            }
+        }
+        return a.hub.SubscribeAll(func(msg events.Message) {
+            if !client.subscribed(msg.ID.String()) && a.canAccess(client.owner, msg.ID) {
+                client.enqueue(msg)
+            }
+        })
+    })
+}
+
+// wsSendBuffer é o tamanho da fila de envio de cada conexão: o bastante
+// para um replay completo enquanto o anterior ainda está sendo enviado
+func (a *API) wsSendBuffer() int {
+    if size := 2 * (a.hub.MaxLog() + 1); size > wsSendBuffer {
+        return size
     }
-    // Remove todas as conexões do mapa
-    delete(wsClients, id)
-    log.Printf("Todas as conexões WebSocket fechadas para o id: %s", id)
+    return wsSendBuffer
+}
+
+func sendWSError(client *wsClient, message string) {
+    reply := events.New(events.Error, "")
+    reply.Error = message
+    client.enqueue(reply)
+}
+
+// broadcast registra a mensagem no log do job e envia para os clientes
+func (a *API) broadcast(msg events.Message) {
+    a.hub.Publish(msg)
+}
+
+func (a *API) closeWebSocketConnections(id jobid.ID) {
+    a.hub.Close(id)
+    a.wsConnections.closeJob(id)
+    a.logger.Printf("Todas as conexões WebSocket fechadas para o id: %s", id)
 }
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+    "errors"
+    "io"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/events"
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
+)
+
+// dialTestWS sobe um servidor com o WebSocketHandler e conecta em /ws
+func dialTestWS(t *testing.T, api *API) *websocket.Conn {
+    t.Helper()
+    r := gin.New()
+    r.GET("/ws", api.WebSocketHandler)
+    server := httptest.NewServer(r)
+    t.Cleanup(server.Close)
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return conn
+}
+
+func TestWebSocketSubscribeReplaysManyJobs(t *testing.T) {
+    api := newTestHandlers(t, &fakeDownloader{})
+
+    // O replay dos jobs juntos passa da fila de envio da conexão
+    const jobCount = 5
+    var ids []string
+    for i := 0; i < jobCount; i++ {
+        id := jobid.New(strings.Repeat("x", i+1))
+        ids = append(ids, id.String())
+        for n := 0; n < api.hub.MaxLog(); n++ {
+            api.hub.Publish(events.New(events.ItemProgress, id))
+        }
+    }
+    if want := jobCount * api.hub.MaxLog(); want <= api.wsSendBuffer() {
+        t.Fatalf("replay de %d mensagens cabe na fila de %d; o teste não cobre o estouro", want, api.wsSendBuffer())
+    }
+
+    conn := dialTestWS(t, api)
+    if err := conn.WriteJSON(wsRequest{Type: "subscribe", IDs: ids}); err != nil {
+        t.Fatal(err)
+    }
+
+    received := map[jobid.ID]int{}
+    subscribed := false
+    conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+    for !subscribed || len(received) < jobCount || received[jobid.ID(ids[jobCount-1])] < api.hub.MaxLog() {
+        var msg events.Message
+        if err := conn.ReadJSON(&msg); err != nil {
+            t.Fatalf("conexão encerrada durante o replay (%d jobs recebidos): %v", len(received), err)
+        }
+        switch msg.Type {
+        case events.ItemProgress:
+            received[msg.ID]++
+        case events.Subscribed:
+            subscribed = true
+        case events.Error:
+            t.Fatalf("erro no subscribe: %s", msg.Error)
+        }
+    }
+    for _, id := range ids {
+        if got := received[jobid.ID(id)]; got != api.hub.MaxLog() {
+            t.Errorf("job %s: %d mensagens no replay, esperado %d", id, got, api.hub.MaxLog())
+        }
+    }
+}
+
+func TestWebSocketSlowConsumerClose(t *testing.T) {
+    // Fila de uma mensagem e writeLoop parado até a segunda estourar
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+        if err != nil {
+            t.Error(err)
+            return
+        }
+        client := newWSClient(conn, "", "", 1, log.New(io.Discard, "", 0))
+        client.enqueue(events.New(events.ItemProgress, "dl_a"))
+        client.enqueue(events.New(events.ItemProgress, "dl_b"))
+        client.writeLoop()
+    }))
+    defer server.Close()
+
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+
+    // O cliente recebe o que estava na fila e depois o close 1013, diferente
+    // do 1000 de um fim normal
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    var msg events.Message
+    if err := conn.ReadJSON(&msg); err != nil || msg.ID != "dl_a" {
+        t.Fatalf("primeira mensagem = %+v (%v), esperado a de dl_a", msg, err)
+    }
+    _, _, err = conn.ReadMessage()
+    var closeErr *websocket.CloseError
+    if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseTryAgainLater || closeErr.Text != wsSlowConsumerReason {
+        t.Fatalf("erro = %v, esperado close %d", err, websocket.CloseTryAgainLater)
+    }
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+    "encoding/json"
+    "log"
+    "sync"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/gorilla/websocket"
+)
+
+const (
+    // Tempo máximo para escrever uma mensagem na conexão
+    wsWriteWait = 10 * time.Second
+    // Tempo sem pong até considerar o cliente morto
+    wsPongWait = 60 * time.Second
+    // Intervalo dos pings; precisa ser menor que wsPongWait
+    wsPingPeriod = (wsPongWait * 9) / 10
+    // Mínimo de mensagens pendentes por conexão; a fila tem espaço para ao
+    // menos dois replays do log de um job (ver API.wsSendBuffer)
+    wsSendBuffer = 4096
+    // Tamanho máximo das mensagens enviadas pelo cliente
+    wsMaxMessageSize = 4096
+)
+
+// wsClient é uma conexão WebSocket. Só a goroutine de writeLoop escreve na
+// conexão; os demais enviam pelo canal send
+type wsClient struct {
+    conn *websocket.Conn
+    // Job que encerra a conexão ao terminar (modo ?id=); vazio no modo multiplexado
+    jobID jobid.ID
+    // Dono do token usado no upgrade; limita quais jobs podem ser assinados
+    owner  string
+    send   chan []byte
+    // Sinalizado pelo writeLoop a cada mensagem enviada, para waitRoom
+    drained chan struct{}
+    logger  *log.Logger
+
+    // Assinaturas no hub de eventos por job; "*" é a assinatura de todos os jobs
+    subsMu sync.Mutex
+    subs   map[string]func()
+
+    done      chan struct{}
+    closeOnce sync.Once
+    // Close frame enviado pelo writeLoop; definidos antes de fechar done
+    closeCode   int
+    closeReason string
+    // Fechado quando writeLoop termina, depois do close frame
+    stopped chan struct{}
+}
+
+func newWSClient(conn *websocket.Conn, jobID jobid.ID, owner string, sendBuffer int, logger *log.Logger) *wsClient {
+    return &wsClient{
+        conn:    conn,
+        jobID:   jobID,
+        owner:   owner,
+        send:    make(chan []byte, sendBuffer),
+        drained: make(chan struct{}, 1),
+        logger:  logger,
+        subs:    make(map[string]func()),
+        done:    make(chan struct{}),
+        stopped: make(chan struct{}),
+    }
+}
+
+// subscribe registra a assinatura; retorna false se o job já estava assinado.
+// O hub é chamado fora de subsMu porque os callbacks do hub consultam subscribed
+func (c *wsClient) subscribe(id string, subscribeFn func() func()) bool {
+    c.subsMu.Lock()
+    if _, ok := c.subs[id]; ok {
+        c.subsMu.Unlock()
+        return false
+    }
+    c.subs[id] = func() {}
+    c.subsMu.Unlock()
+
+    unsubscribe := subscribeFn()
+
+    c.subsMu.Lock()
+    defer c.subsMu.Unlock()
+    if _, ok := c.subs[id]; !ok {
+        // Cancelada enquanto assinava
+        unsubscribe()
+        return true
+    }
+    c.subs[id] = unsubscribe
+    return true
+}
+
+func (c *wsClient) unsubscribe(id string) bool {
+    c.subsMu.Lock()
+    unsubscribe, ok := c.subs[id]
+    delete(c.subs, id)
+    c.subsMu.Unlock()
+
+    if ok {
+        unsubscribe()
+    }
+    return ok
+}
+
+func (c *wsClient) unsubscribeAll() {
+    c.subsMu.Lock()
+    subs := c.subs
+    c.subs = make(map[string]func())
+    c.subsMu.Unlock()
+
+    for _, unsubscribe := range subs {
+        unsubscribe()
+    }
+}
+
+// subscribed indica se o job tem assinatura própria (fora do "*")
+func (c *wsClient) subscribed(id string) bool {
+    c.subsMu.Lock()
+    defer c.subsMu.Unlock()
+    _, ok := c.subs[id]
+    return ok
+}
+
+// enqueue coloca a mensagem na fila de envio sem bloquear. Um cliente que
+// não acompanha é desconectado e pode retomar com since
+func (c *wsClient) enqueue(msg any) {
+    data, err := json.Marshal(msg)
+    if err != nil {
+        return
+    }
+    select {
+    case <-c.done:
+    case c.send <- data:
+    default:
+        c.logger.Printf("Cliente WebSocket lento desconectado para o id: %s", c.jobID)
+        c.closeWith(websocket.CloseTryAgainLater, wsSlowConsumerReason)
+    }
+}
+
+// waitRoom espera até a fila de envio ter n posições livres (ou esvaziar, se
+// n passa da capacidade), para que um replay grande não a estoure. Retorna
+// false se a conexão foi fechada antes
+func (c *wsClient) waitRoom(n int) bool {
+    if n > cap(c.send) {
+        n = cap(c.send)
+    }
+    for cap(c.send)-len(c.send) < n {
+        select {
+        case <-c.drained:
+        case <-c.done:
+            return false
+        }
+    }
+    return true
+}
+
+// close encerra a conexão depois de enviar o que já está na fila
+func (c *wsClient) close() {
+    c.closeWith(websocket.CloseNormalClosure, "")
+}
+
+// closeWith é close com o código do close frame; só o primeiro fechamento vale
+func (c *wsClient) closeWith(code int, reason string) {
+    c.closeOnce.Do(func() {
+        c.closeCode = code
+        c.closeReason = reason
+        close(c.done)
+    })
+}
+
+// writeLoop é o único escritor da conexão: envia as mensagens da fila e os pings
+func (c *wsClient) writeLoop() {
+    ticker := time.NewTicker(wsPingPeriod)
+    defer func() {
+        ticker.Stop()
+        c.conn.Close()
+        close(c.stopped)
+    }()
+
+    for {
+        select {
+        case data := <-c.send:
+            if err := c.write(websocket.TextMessage, data); err != nil {
+                c.close()
+                return
+            }
+            select {
+            case c.drained <- struct{}{}:
+            default:
+            }
+        case <-ticker.C:
+            if err := c.write(websocket.PingMessage, nil); err != nil {
+                c.close()
+                return
+            }
+        case <-c.done:
+            // Esvazia a fila (ex.: job_done enviado logo antes do fechamento)
+            for {
+                select {
+                case data := <-c.send:
+                    if err := c.write(websocket.TextMessage, data); err != nil {
+                        return
+                    }
+                default:
+                    c.write(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeReason))
+                    return
+                }
+            }
+        }
+    }
+}
+
+func (c *wsClient) write(messageType int, data []byte) error {
+    c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+    return c.conn.WriteMessage(messageType, data)
+}
+
+// readLoop lê as mensagens do cliente até a conexão cair; cada pong renova o prazo
+func (c *wsClient) readLoop(onMessage func(data []byte)) {
+    defer c.close()
+
+    c.conn.SetReadLimit(wsMaxMessageSize)
+    c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+    c.conn.SetPongHandler(func(string) error {
+        return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+    })
+
+    for {
+        _, data, err := c.conn.ReadMessage()
+        if err != nil {
+            return
+        }
+        onMessage(data)
+    }
+}
+
+// wsHub guarda as conexões abertas de cada job; as multiplexadas ficam
+// no id vazio
+type wsHub struct {
+    mu      sync.Mutex
+    clients map[jobid.ID]map[*wsClient]struct{}
+    // Depois de closeAll novas conexões são recusadas
+    closed bool
+}
+
+func newWSHub() *wsHub {
+    return &wsHub{clients: make(map[jobid.ID]map[*wsClient]struct{})}
+}
+
+// add registra a conexão; retorna false se o hub já foi fechado
+func (h *wsHub) add(c *wsClient) bool {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    if h.closed {
+        return false
+    }
+    if h.clients[c.jobID] == nil {
+        h.clients[c.jobID] = make(map[*wsClient]struct{})
+    }
+    h.clients[c.jobID][c] = struct{}{}
+    return true
+}
+
+func (h *wsHub) remove(c *wsClient) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    delete(h.clients[c.jobID], c)
+    if len(h.clients[c.jobID]) == 0 {
+        delete(h.clients, c.jobID)
+    }
+}
+
+// closeJob fecha todas as conexões do job
+func (h *wsHub) closeJob(id jobid.ID) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    for c := range h.clients[id] {
+        c.close()
+    }
+    delete(h.clients, id)
+}
+
+// closeAll fecha todas as conexões com o close frame normal, recusa as novas
+// e espera até wsWriteWait que os close frames sejam enviados
+func (h *wsHub) closeAll() {
+    h.mu.Lock()
+    h.closed = true
+    var closing []*wsClient
+    for id, clients := range h.clients {
+        for c := range clients {
+            c.close()
+            closing = append(closing, c)
+        }
+        delete(h.clients, id)
+    }
+    h.mu.Unlock()
+
+    timeout := time.After(wsWriteWait)
+    for _, c := range closing {
+        select {
+        case <-c.stopped:
+        case <-timeout:
+            return
+        }
+    }
+}
@@ -0,0 +1,52 @@
+package jobid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Prefix identifica os ids de download
+const Prefix = "dl_"
+
+// Tamanho em hexadecimal do sha256 que segue o prefixo
+const hashLen = sha256.Size * 2
+
+// ErrInvalid é retornado por Parse para qualquer id fora do formato
+var ErrInvalid = errors.New("id inválido: esperado dl_ seguido de 64 caracteres hexadecimais")
+
+// ID é o id de um job e o nome do seu diretório em DOWNLOAD_DIR. Só é
+// criado por New ou Parse, então é seguro usá-lo em caminhos de arquivo
+type ID string
+
+// New gera o id a partir dos parâmetros normalizados do download
+func New(input string) ID {
+	sum := sha256.Sum256([]byte(input))
+	return ID(Prefix + hex.EncodeToString(sum[:]))
+}
+
+// Parse valida um id vindo de fora (query, mensagem, disco): "dl_" seguido
+// de 64 caracteres hexadecimais minúsculos
+func Parse(s string) (ID, error) {
+	hash, ok := strings.CutPrefix(s, Prefix)
+	if !ok || len(hash) != hashLen {
+		return "", ErrInvalid
+	}
+	for _, r := range hash {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return "", ErrInvalid
+		}
+	}
+	return ID(s), nil
+}
+
+// Valid indica se s é um id válido
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+func (id ID) String() string {
+	return string(id)
+}
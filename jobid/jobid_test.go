@@ -0,0 +1,60 @@
+package jobid
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := "dl_" + strings.Repeat("0123456789abcdef", 4)
+
+	tests := []struct {
+		name string
+		id   string
+		ok   bool
+	}{
+		{"válido", valid, true},
+		{"gerado por New", New("https://youtube.com/watch?v=x|mp4|720p|false|").String(), true},
+		{"vazio", "", false},
+		{"só o prefixo", "dl_", false},
+		{"sem prefixo", strings.TrimPrefix(valid, "dl_"), false},
+		{"outro prefixo", "pl_" + strings.TrimPrefix(valid, "dl_"), false},
+		{"curto", valid[:len(valid)-1], false},
+		{"longo", valid + "0", false},
+		{"maiúsculas", strings.ToUpper(valid[:3]) + valid[3:], false},
+		{"hex maiúsculo", "dl_" + strings.Repeat("ABCDEF0123456789", 4), false},
+		{"fora do hex", "dl_" + strings.Repeat("g", 64), false},
+		{"path traversal", "dl_../../../../etc/passwd" + strings.Repeat("a", 39), false},
+		{"barra", "dl_" + strings.Repeat("a", 63) + "/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := Parse(tt.id)
+			if tt.ok {
+				if err != nil || id.String() != tt.id {
+					t.Fatalf("Parse(%q) = %q, %v; esperado válido", tt.id, id, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) || id != "" {
+				t.Fatalf("Parse(%q) = %q, %v; esperado ErrInvalid", tt.id, id, err)
+			}
+			if Valid(tt.id) {
+				t.Fatalf("Valid(%q) = true", tt.id)
+			}
+		})
+	}
+}
+
+func TestNewIsDeterministic(t *testing.T) {
+	a := New("https://youtube.com/watch?v=x|mp4|720p|false|")
+	b := New("https://youtube.com/watch?v=x|mp4|720p|false|")
+	c := New("https://youtube.com/watch?v=x|mp3|720p|false|")
+	if a != b {
+		t.Errorf("New não é determinístico: %s != %s", a, b)
+	}
+	if a == c {
+		t.Errorf("parâmetros diferentes geraram o mesmo id %s", a)
+	}
+}
@@ -0,0 +1,638 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+var (
+	// ErrQueueFull é retornado por Submit quando a fila já atingiu o tamanho máximo
+	ErrQueueFull = errors.New("fila de downloads cheia")
+	// ErrNotFound é retornado quando o id não está no registro
+	ErrNotFound = errors.New("job não encontrado")
+	// ErrFinished é retornado ao cancelar um job que já terminou
+	ErrFinished = errors.New("job já terminou")
+	// ErrCancelled é o erro de um job cancelado
+	ErrCancelled = errors.New("job cancelado")
+	// ErrOwnerLimit é retornado por Submit quando o dono já tem o máximo de
+	// jobs ativos
+	ErrOwnerLimit = errors.New("limite de jobs simultâneos do dono atingido")
+	// ErrShuttingDown é retornado por Submit depois de Shutdown
+	ErrShuttingDown = errors.New("servidor encerrando, job não aceito")
+	// ErrInterrupted é o erro de um job encerrado pelo Shutdown; ele continua
+	// no journal e é retomado na próxima inicialização
+	ErrInterrupted = errors.New("job interrompido pelo encerramento do servidor")
+)
+
+// Tempo para o yt-dlp sair depois de ter o contexto cancelado no Shutdown
+const killTimeout = 10 * time.Second
+
+// DefaultRetention é quanto tempo um job encerrado continua consultável
+const DefaultRetention = time.Hour
+
+// State é o estado de um job no ciclo de vida da fila
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Params guarda os parâmetros originais de um download
+type Params struct {
+	URL      string `json:"url"`
+	Format   string `json:"format"`
+	Quality  string `json:"quality"`
+	Playlist bool   `json:"playlist"`
+	Index    string `json:"index"`
+	Dir      string `json:"-"`
+}
+
+// Item é um item já processado dentro de um job; Error vem preenchido
+// quando o item falhou
+type Item struct {
+	Index    int    `json:"index"`
+	Title    string `json:"title"`
+	Filename string `json:"filename,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Progress é o progresso do item que está sendo baixado
+type Progress struct {
+	Index   int     `json:"index"`
+	Title   string  `json:"title"`
+	Percent float64 `json:"percent"`
+	Speed   float64 `json:"speed"`
+	ETA     int     `json:"eta"`
+	Stage   string  `json:"stage,omitempty"`
+}
+
+// Status é a fotografia de um job exposta pela API
+type Status struct {
+	ID         jobid.ID   `json:"id"`
+	State      State      `json:"state"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Params     Params     `json:"params"`
+	Total      int        `json:"total"`
+	Current    *Progress  `json:"current,omitempty"`
+	Items      []Item     `json:"items"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Job representa um download enfileirado
+type Job struct {
+	ID     jobid.ID
+	Params Params
+
+	mu         sync.Mutex
+	state      State
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	total      int
+	current    *Progress
+	items      []Item
+
+	// Quem pediu o job; requisições coalescidas somam donos
+	owners map[string]bool
+	// Encerrado pelo Shutdown; fica no journal para ser retomado
+	interrupted bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+	// Fechado depois do OnSubmit; o worker não inicia o job antes disso
+	admitted chan struct{}
+}
+
+// Context é cancelado quando o job é cancelado; o processo do yt-dlp
+// deve ser amarrado a ele
+func (j *Job) Context() context.Context {
+	return j.ctx
+}
+
+// Done é fechado quando o job termina (com ou sem erro)
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Err retorna o erro do job; só é válido depois de Done ser fechado
+func (j *Job) Err() error {
+	return j.err
+}
+
+// OwnedBy indica se o job foi pedido pelo dono informado
+func (j *Job) OwnedBy(owner string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.owners[owner]
+}
+
+func (j *Job) addOwner(owner string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.owners[owner] = true
+}
+
+// ownerList exige j.mu
+func (j *Job) ownerList() []string {
+	owners := make([]string, 0, len(j.owners))
+	for owner := range j.owners {
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// SetTotal registra quantos itens o job vai baixar
+func (j *Job) SetTotal(total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.total = total
+}
+
+// SetProgress atualiza o progresso do item atual
+func (j *Job) SetProgress(progress Progress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.current = &progress
+}
+
+// AddItem registra um item concluído ou que falhou; sem Index, o item
+// recebe a próxima posição do job
+func (j *Job) AddItem(item Item) Item {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if item.Index == 0 {
+		item.Index = len(j.items) + 1
+	}
+	j.items = append(j.items, item)
+	j.current = nil
+	return item
+}
+
+// Status retorna uma cópia do estado atual do job
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := Status{
+		ID:        j.ID,
+		State:     j.state,
+		CreatedAt: j.createdAt,
+		Params:    j.Params,
+		Total:     j.total,
+		Items:     append([]Item{}, j.items...),
+	}
+	if j.current != nil {
+		current := *j.current
+		status.Current = &current
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		status.FinishedAt = &finishedAt
+		if j.err != nil {
+			status.Error = j.err.Error()
+		}
+	}
+	return status
+}
+
+// active indica se o job ainda está na fila ou rodando
+func (j *Job) active() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.activeLocked()
+}
+
+func (j *Job) activeLocked() bool {
+	return j.state == StateQueued || j.state == StateRunning
+}
+
+// Interrupted indica se o job foi encerrado pelo Shutdown, e não cancelado;
+// ele continua no journal e volta na próxima inicialização
+func (j *Job) Interrupted() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.interrupted
+}
+
+// expired indica se o job terminou há mais de retention
+func (j *Job) expired(now time.Time, retention time.Duration) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finishedAt.IsZero() && now.Sub(j.finishedAt) > retention
+}
+
+// start passa o job para running; retorna false se ele foi cancelado na fila
+func (j *Job) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.state != StateQueued {
+		return false
+	}
+	j.state = StateRunning
+	j.startedAt = time.Now()
+	return true
+}
+
+// finish registra o resultado do job e libera quem espera por Done
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case j.interrupted:
+		j.state = StateCancelled
+		err = ErrInterrupted
+	case j.ctx.Err() != nil:
+		j.state = StateCancelled
+		err = ErrCancelled
+	case err != nil:
+		j.state = StateFailed
+	default:
+		j.state = StateCompleted
+	}
+	j.err = err
+	j.finishedAt = time.Now()
+	j.cancel()
+	close(j.done)
+}
+
+// RunFunc executa um job; o erro retornado marca o job como falho
+type RunFunc func(j *Job) error
+
+// Manager mantém a fila FIFO de jobs, o pool de workers que os executa
+// e o registro de todos os jobs conhecidos
+type Manager struct {
+	queue   chan *Job
+	run     RunFunc
+	journal *Journal
+	logger  *log.Logger
+	// Chamada para cada job novo, antes de ele poder rodar
+	onSubmit func(*Job)
+	// Tempo que os jobs encerrados ficam no registro
+	retention time.Duration
+
+	mu   sync.RWMutex
+	jobs map[jobid.ID]*Job
+	// Depois do Shutdown nenhum job é aceito nem iniciado
+	closed  bool
+	running sync.WaitGroup
+}
+
+// NewManager cria a fila e inicia os workers. O journal é opcional; sem ele
+// os jobs pendentes se perdem em um restart. Com logger nil é usado o
+// logger padrão do pacote log
+func NewManager(workers, maxQueue int, journal *Journal, run RunFunc, logger *log.Logger) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	m := &Manager{
+		queue:   make(chan *Job, maxQueue),
+		run:     run,
+		journal: journal,
+		logger:  logger,
+		jobs:    make(map[jobid.ID]*Job),
+
+		retention: DefaultRetention,
+	}
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+	return m
+}
+
+// Submit coloca um novo job no fim da fila, sem bloquear. Se já existe um
+// job com o mesmo id na fila ou rodando, ele é retornado em vez de criar
+// outro e owner passa a ser também dono dele. maxActive limita os jobs na
+// fila ou rodando de owner (0 = sem limite); entrar num job de que owner já
+// é dono não conta. O limite é conferido com o lock do Manager, então
+// requisições simultâneas não passam dele
+func (m *Manager) Submit(id jobid.ID, params Params, owner string, maxActive int) (*Job, error) {
+	m.mu.Lock()
+	job, created, err := m.submit(id, params, owner, maxActive)
+	onSubmit := m.onSubmit
+	m.mu.Unlock()
+
+	if created {
+		admit(job, onSubmit)
+	}
+	return job, err
+}
+
+// submit registra o job com m.mu; created indica um job novo, que ainda
+// precisa passar por admit
+func (m *Manager) submit(id jobid.ID, params Params, owner string, maxActive int) (job *Job, created bool, err error) {
+	if m.closed {
+		return nil, false, ErrShuttingDown
+	}
+	m.sweep()
+	job, ok := m.jobs[id]
+	active := ok && job.active()
+	if active && job.OwnedBy(owner) {
+		return job, false, nil
+	}
+	if maxActive > 0 && m.activeOwnedBy(owner) >= maxActive {
+		return nil, false, ErrOwnerLimit
+	}
+	if active {
+		job.addOwner(owner)
+		m.save(job)
+		return job, false, nil
+	}
+
+	// Grava no journal antes de enfileirar para não sobrescrever o estado
+	// gravado pelo worker
+	job = newJob(id, params, time.Now())
+	job.addOwner(owner)
+	m.save(job)
+	select {
+	case m.queue <- job:
+	default:
+		job.cancel()
+		m.forget(id)
+		return nil, false, ErrQueueFull
+	}
+	m.jobs[id] = job
+	return job, true, nil
+}
+
+// activeOwnedBy conta os jobs na fila ou rodando de owner; chamado com m.mu
+func (m *Manager) activeOwnedBy(owner string) int {
+	count := 0
+	for _, job := range m.jobs {
+		if job.active() && job.OwnedBy(owner) {
+			count++
+		}
+	}
+	return count
+}
+
+// OnSubmit registra fn para ser chamada com cada job novo (Submit ou
+// Resume) antes de ele começar a rodar, por exemplo para descartar o
+// progresso de uma execução anterior com o mesmo id. fn roda fora do lock
+// do Manager, então pode tomar outros locks e chamar os métodos dele
+func (m *Manager) OnSubmit(fn func(*Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onSubmit = fn
+}
+
+// admit chama onSubmit e libera o job para os workers. Roda sem m.mu: o
+// OnSubmit da API toma o lock do hub, cujos assinantes consultam o Manager
+func admit(job *Job, onSubmit func(*Job)) {
+	if onSubmit != nil {
+		onSubmit(job)
+	}
+	close(job.admitted)
+}
+
+// Resume recoloca na fila, na ordem original, os jobs que o journal registrou
+// como não terminados. Deve ser chamado uma vez na inicialização
+func (m *Manager) Resume() (int, error) {
+	if m.journal == nil {
+		return 0, nil
+	}
+	records, err := m.journal.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	var resumed []*Job
+	m.mu.Lock()
+	for _, rec := range records {
+		if job, ok := m.jobs[rec.ID]; ok && job.active() {
+			continue
+		}
+		params := rec.Params
+		params.Dir = rec.Dir
+		job := newJob(rec.ID, params, rec.CreatedAt)
+		for _, owner := range rec.Owners {
+			job.addOwner(owner)
+		}
+		m.jobs[rec.ID] = job
+		resumed = append(resumed, job)
+	}
+	onSubmit := m.onSubmit
+	m.mu.Unlock()
+
+	for _, job := range resumed {
+		admit(job, onSubmit)
+	}
+
+	// Os jobs retomados podem passar do limite da fila, então o envio bloqueia
+	// em background até haver espaço
+	go func() {
+		for _, job := range resumed {
+			m.queue <- job
+		}
+	}()
+	return len(resumed), nil
+}
+
+// SetRetention define quanto tempo os jobs encerrados continuam no registro
+// (padrão DefaultRetention); depois disso Get não os encontra mais
+func (m *Manager) SetRetention(retention time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = retention
+}
+
+// Get busca um job no registro pelo id
+func (m *Manager) Get(id jobid.ID) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	if ok && job.expired(time.Now(), m.retention) {
+		// Removido no próximo Submit
+		return nil, false
+	}
+	return job, ok
+}
+
+// sweep descarta do registro os jobs encerrados há mais de retention, para
+// que a memória não cresça com cada download distinto. Chamado com m.mu
+func (m *Manager) sweep() {
+	now := time.Now()
+	for id, job := range m.jobs {
+		if job.expired(now, m.retention) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Cancel cancela um job: se ainda está na fila ele nunca será executado,
+// se está rodando o contexto dele é cancelado
+func (m *Manager) Cancel(id jobid.ID) (*Job, error) {
+	job, ok := m.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	switch job.state {
+	case StateQueued:
+		job.state = StateCancelled
+		job.err = ErrCancelled
+		job.finishedAt = time.Now()
+		job.cancel()
+		close(job.done)
+		m.forget(job.ID)
+	case StateRunning:
+		job.cancel()
+	default:
+		return job, ErrFinished
+	}
+	return job, nil
+}
+
+// Active retorna os jobs do registro que ainda estão na fila ou rodando
+func (m *Manager) Active() []*Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var active []*Job
+	for _, job := range m.jobs {
+		if job.active() {
+			active = append(active, job)
+		}
+	}
+	return active
+}
+
+func (m *Manager) worker() {
+	for job := range m.queue {
+		<-job.admitted
+		// Jobs ainda na fila durante o Shutdown continuam no journal
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			continue
+		}
+		m.running.Add(1)
+		m.mu.Unlock()
+
+		if job.start() {
+			m.save(job)
+			job.finish(m.run(job))
+			if !job.interrupted {
+				m.forget(job.ID)
+			}
+		}
+		m.running.Done()
+	}
+}
+
+// Shutdown para de aceitar e de iniciar jobs e espera os que estão rodando.
+// Se ctx acabar antes, os jobs restantes são interrompidos (o yt-dlp é
+// encerrado) e ficam no journal para serem retomados, e o erro de ctx é
+// retornado
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	for _, job := range m.Active() {
+		job.mu.Lock()
+		if job.state == StateRunning {
+			job.interrupted = true
+			job.cancel()
+		}
+		job.mu.Unlock()
+	}
+	select {
+	case <-done:
+	case <-time.After(killTimeout):
+		m.logger.Printf("yt-dlp não encerrou em %s após o cancelamento", killTimeout)
+	}
+	return ctx.Err()
+}
+
+// save grava o estado atual do job no journal, se ele ainda está ativo. A
+// gravação é feita com job.mu: um finish concorrente espera por ela, então o
+// forget que vem depois do finish sempre remove o registro gravado
+func (m *Manager) save(job *Job) {
+	if m.journal == nil {
+		return
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if !job.activeLocked() {
+		return
+	}
+	err := m.journal.Save(Record{
+		ID:        job.ID,
+		Params:    job.Params,
+		Dir:       job.Params.Dir,
+		State:     job.state,
+		CreatedAt: job.createdAt,
+		Owners:    job.ownerList(),
+	})
+	if err != nil {
+		m.logger.Printf("Erro ao gravar o job %s no journal: %v", job.ID, err)
+	}
+}
+
+// forget remove do journal um job que terminou
+func (m *Manager) forget(id jobid.ID) {
+	if m.journal == nil {
+		return
+	}
+	if err := m.journal.Remove(id); err != nil {
+		m.logger.Printf("Erro ao remover o job %s do journal: %v", id, err)
+	}
+}
+
+func newJob(id jobid.ID, params Params, createdAt time.Time) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Job{
+		ID:        id,
+		Params:    params,
+		state:     StateQueued,
+		createdAt: createdAt,
+		owners:    make(map[string]bool),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		admitted:  make(chan struct{}),
+	}
+}
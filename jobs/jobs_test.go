@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+// newTestManager cria um Manager sem journal que roda run
+func newTestManager(t *testing.T, workers int, run RunFunc) *Manager {
+	t.Helper()
+	m := NewManager(workers, 10, nil, run, log.New(io.Discard, "", 0))
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	return m
+}
+
+// newTestManagerWithJournal cria um Manager de um worker que grava em journal
+func newTestManagerWithJournal(t *testing.T, journal *Journal, run RunFunc) *Manager {
+	t.Helper()
+	m := NewManager(1, 10, journal, run, log.New(io.Discard, "", 0))
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	return m
+}
+
+// wait falha o teste se ch não fechar em 5s
+func wait(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("tempo esgotado esperando %s", what)
+	}
+}
+
+func TestOnSubmitRunsWithoutLock(t *testing.T) {
+	var mu sync.Mutex
+	hooked := map[jobid.ID]bool{}
+	startedEarly := make(chan jobid.ID, 1)
+
+	m := newTestManager(t, 2, func(j *Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !hooked[j.ID] {
+			startedEarly <- j.ID
+		}
+		return nil
+	})
+
+	// O hook da API toma o lock do hub, cujos assinantes chamam Get; com o
+	// hook rodando sob o lock do Manager isso travava
+	m.OnSubmit(func(j *Job) {
+		if _, ok := m.Get(j.ID); !ok {
+			t.Errorf("job %s não encontrado dentro do OnSubmit", j.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		hooked[j.ID] = true
+		mu.Unlock()
+	})
+
+	submitted := make(chan struct{})
+	var jobs []*Job
+	go func() {
+		defer close(submitted)
+		for _, input := range []string{"a", "b", "c"} {
+			job, err := m.Submit(jobid.New(input), Params{}, "", 0)
+			if err != nil {
+				t.Errorf("Submit(%q): %v", input, err)
+				return
+			}
+			jobs = append(jobs, job)
+		}
+	}()
+	wait(t, submitted, "o Submit com OnSubmit chamando o Manager")
+
+	for _, job := range jobs {
+		wait(t, job.Done(), "o job "+job.ID.String())
+	}
+	select {
+	case id := <-startedEarly:
+		t.Fatalf("job %s começou antes do OnSubmit terminar", id)
+	default:
+	}
+}
+
+func TestManagerRetention(t *testing.T) {
+	m := newTestManager(t, 1, func(j *Job) error { return nil })
+	m.SetRetention(50 * time.Millisecond)
+
+	job, err := m.Submit(jobid.New("a"), Params{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, job.Done(), "o job")
+
+	// Encerrado, o job continua consultável até o fim da retenção
+	got, ok := m.Get(job.ID)
+	if !ok || got.Status().State != StateCompleted || got.Status().FinishedAt == nil {
+		t.Fatalf("job encerrado fora do registro: %v", ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := m.Get(job.ID); ok {
+		t.Fatal("job consultável depois da retenção")
+	}
+
+	// O próximo Submit descarta os expirados do registro
+	if _, err := m.Submit(jobid.New("b"), Params{}, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	m.mu.RLock()
+	_, kept := m.jobs[job.ID]
+	m.mu.RUnlock()
+	if kept {
+		t.Fatal("job expirado mantido no registro depois do Submit")
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	started := make(chan jobid.ID, 2)
+	m := newTestManager(t, 1, func(j *Job) error {
+		started <- j.ID
+		<-j.Context().Done()
+		return j.Context().Err()
+	})
+
+	running, err := m.Submit(jobid.New("rodando"), Params{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := <-started; id != running.ID {
+		t.Fatalf("job %s iniciado, esperado %s", id, running.ID)
+	}
+	queued, err := m.Submit(jobid.New("na fila"), Params{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Na fila: encerra na hora e nunca roda
+	if _, err := m.Cancel(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, queued.Done(), "o job cancelado na fila")
+	if !errors.Is(queued.Err(), ErrCancelled) || queued.Status().StartedAt != nil {
+		t.Fatalf("job da fila: erro %v, status %+v", queued.Err(), queued.Status())
+	}
+
+	// Rodando: o contexto é cancelado e o job termina como cancelado
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, running.Done(), "o job cancelado rodando")
+	if status := running.Status(); status.State != StateCancelled || !errors.Is(running.Err(), ErrCancelled) {
+		t.Fatalf("job rodando: estado %s, erro %v", status.State, running.Err())
+	}
+
+	if _, err := m.Cancel(running.ID); !errors.Is(err, ErrFinished) {
+		t.Fatalf("cancelar job encerrado: erro = %v, esperado %v", err, ErrFinished)
+	}
+	if _, err := m.Cancel(jobid.New("desconhecido")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cancelar job desconhecido: erro = %v, esperado %v", err, ErrNotFound)
+	}
+
+	// O worker segue para os próximos jobs
+	next, err := m.Submit(jobid.New("próximo"), Params{}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-started:
+		if id != next.ID {
+			t.Fatalf("job %s iniciado, esperado %s", id, next.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker parado depois do cancelamento")
+	}
+	m.Cancel(next.ID)
+}
+
+func TestManagerCoalesce(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := 0
+	m := newTestManager(t, 2, func(j *Job) error {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		<-release
+		return nil
+	})
+	id := jobid.New("mesmo vídeo")
+
+	// Pedidos simultâneos do mesmo id viram um único job com todos os donos
+	owners := []string{"alice", "bob", "carol", "alice"}
+	got := make([]*Job, len(owners))
+	var wg sync.WaitGroup
+	for i, owner := range owners {
+		wg.Add(1)
+		go func(i int, owner string) {
+			defer wg.Done()
+			job, err := m.Submit(id, Params{}, owner, 0)
+			if err != nil {
+				t.Errorf("Submit(%s): %v", owner, err)
+			}
+			got[i] = job
+		}(i, owner)
+	}
+	wg.Wait()
+	for _, job := range got {
+		if job != got[0] {
+			t.Fatal("pedidos do mesmo id criaram jobs diferentes")
+		}
+	}
+	for _, owner := range owners {
+		if !got[0].OwnedBy(owner) {
+			t.Errorf("%s não é dono do job coalescido", owner)
+		}
+	}
+	close(release)
+	wait(t, got[0].Done(), "o job coalescido")
+
+	mu.Lock()
+	if runs != 1 {
+		t.Fatalf("job coalescido rodou %d vezes, esperado 1", runs)
+	}
+	mu.Unlock()
+
+	// Depois de encerrado, o mesmo id gera um job novo (ex.: retry)
+	retry, err := m.Submit(id, Params{}, "dave", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry == got[0] || retry.OwnedBy("alice") {
+		t.Fatal("Submit depois do fim reaproveitou o job encerrado")
+	}
+	wait(t, retry.Done(), "o retry")
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+// JournalDirName é o diretório, dentro de DOWNLOAD_DIR, onde ficam os jobs pendentes
+const JournalDirName = ".jobs"
+
+// Record é o que o journal guarda de cada job ainda não terminado
+type Record struct {
+	ID        jobid.ID  `json:"id"`
+	Params    Params    `json:"params"`
+	Dir       string    `json:"dir"`
+	State     State     `json:"state"`
+	CreatedAt time.Time `json:"createdAt"`
+	Owners    []string  `json:"owners,omitempty"`
+}
+
+// Journal persiste em disco os jobs na fila ou rodando, um arquivo JSON por
+// job, para que possam ser retomados depois de um restart
+type Journal struct {
+	dir string
+}
+
+// OpenJournal cria (se preciso) o diretório do journal
+func OpenJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Journal{dir: dir}, nil
+}
+
+// Save grava o registro do job, substituindo o anterior
+func (j *Journal) Save(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	// Grava em arquivo temporário e renomeia para não deixar JSON pela metade
+	tmp := j.path(rec.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path(rec.ID))
+}
+
+// Remove apaga o registro de um job que terminou
+func (j *Journal) Remove(id jobid.ID) error {
+	err := os.Remove(j.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Pending lista os jobs não terminados, do mais antigo para o mais novo.
+// Registros com estado final (de um job que terminou sem chegar a ser
+// removido) são ignorados
+func (j *Journal) Pending() ([]Record, error) {
+	files, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(data, &rec); err != nil || !jobid.Valid(rec.ID.String()) {
+			continue
+		}
+		if rec.State != StateQueued && rec.State != StateRunning {
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(a, b int) bool {
+		return records[a].CreatedAt.Before(records[b].CreatedAt)
+	})
+	return records, nil
+}
+
+func (j *Journal) path(id jobid.ID) string {
+	return filepath.Join(j.dir, id.String()+".json")
+}
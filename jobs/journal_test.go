@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Arthur-Scaratti/yt-api/jobid"
+)
+
+func TestJournalPending(t *testing.T) {
+	journal, err := OpenJournal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	records := []Record{
+		{ID: jobid.New("rodando"), State: StateRunning, CreatedAt: now.Add(2 * time.Second)},
+		{ID: jobid.New("na fila"), State: StateQueued, CreatedAt: now},
+		{ID: jobid.New("concluído"), State: StateCompleted, CreatedAt: now},
+		{ID: jobid.New("falhou"), State: StateFailed, CreatedAt: now},
+		{ID: jobid.New("cancelado"), State: StateCancelled, CreatedAt: now},
+	}
+	for _, rec := range records {
+		if err := journal.Save(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Arquivos que não são registros válidos são ignorados
+	if err := os.WriteFile(journal.path("dl_invalido"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := journal.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].ID != records[1].ID || pending[1].ID != records[0].ID {
+		t.Fatalf("Pending = %+v, esperado os jobs na fila e rodando, do mais antigo ao mais novo", pending)
+	}
+}
+
+func TestManagerResume(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(io.Discard, "", 0)
+
+	// Primeira execução: o Shutdown interrompe o job rodando e o da fila
+	// nem começa; os dois ficam no journal
+	release := make(chan struct{})
+	first := NewManager(1, 10, journal, func(j *Job) error {
+		select {
+		case <-j.Context().Done():
+		case <-release:
+		}
+		return nil
+	}, logger)
+	running, err := first.Submit(jobid.New("a"), Params{URL: "a"}, "alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Submit(jobid.New("b"), Params{URL: "b"}, "bob", 0); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); running.Status().State != StateRunning; {
+		if time.Now().After(deadline) {
+			t.Fatal("job não começou")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first.Shutdown(ctx)
+	if !running.Interrupted() {
+		t.Fatal("job rodando não foi marcado como interrompido")
+	}
+
+	// Segunda execução: os dois voltam na ordem original, com os donos
+	ran := make(chan string, 2)
+	second := newTestManagerWithJournal(t, journal, func(j *Job) error {
+		ran <- j.Params.URL
+		return nil
+	})
+	n, err := second.Resume()
+	if err != nil || n != 2 {
+		t.Fatalf("Resume = %d, %v; esperado 2 jobs", n, err)
+	}
+	for _, want := range []string{"a", "b"} {
+		select {
+		case got := <-ran:
+			if got != want {
+				t.Fatalf("job %q retomado fora de ordem, esperado %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("job retomado não rodou")
+		}
+	}
+	job, ok := second.Get(jobid.New("b"))
+	if !ok || !job.OwnedBy("bob") {
+		t.Fatal("job retomado sem o dono original")
+	}
+	wait(t, job.Done(), "o job retomado")
+
+	// Jobs terminados saem do journal
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		pending, _ := journal.Pending()
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("journal ainda tem %d jobs depois de terminados", len(pending))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerSaveFinishedJob(t *testing.T) {
+	journal, err := OpenJournal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestManagerWithJournal(t, journal, func(j *Job) error { return nil })
+	job, err := m.Submit(jobid.New("a"), Params{}, "alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, job.Done(), "o job")
+
+	// Um Submit coalescido que grava depois do fim não recria o registro
+	m.save(job)
+	if _, err := os.Stat(journal.path(job.ID)); !os.IsNotExist(err) {
+		t.Fatalf("registro de job terminado gravado no journal: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package ytapi
+
+import (
+    "log"
+    "os"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/auth"
+    "github.com/Arthur-Scaratti/yt-api/config"
+    "github.com/Arthur-Scaratti/yt-api/downloader"
+    "github.com/Arthur-Scaratti/yt-api/handlers"
+)
+
+// Option ajusta a montagem do servidor em New, NewHandler, RegisterRoutes e
+// Start. As opções que alteram a configuração são aplicadas, na ordem em que
+// foram passadas, sobre a configuração base (WithConfig ou config.Load()),
+// antes da validação
+type Option func(*options)
+
+type options struct {
+    config *config.Config
+    // Ajustes na configuração, reaplicados a cada reload
+    overrides []func(*config.Config)
+
+    downloader downloader.Downloader
+    storage    handlers.Storage
+    logger     *log.Logger
+}
+
+// override registra um ajuste na configuração base
+func override(fn func(*config.Config)) Option {
+    return func(o *options) {
+        o.overrides = append(o.overrides, fn)
+    }
+}
+
+// Routes são os caminhos das rotas da API. Campos vazios mantêm o caminho
+// da configuração base
+type Routes struct {
+    Download  string
+    Playlist  string
+    WebSocket string
+    Jobs      string
+    Events    string
+    Fetch     string
+    Admin     string
+}
+
+// WithConfig usa cfg como configuração base no lugar de config.Load(). cfg
+// não é alterado pelas demais opções
+func WithConfig(cfg *config.Config) Option {
+    return func(o *options) {
+        o.config = cfg
+    }
+}
+
+// WithDownloadDir define o diretório dos downloads (DOWNLOAD_DIR)
+func WithDownloadDir(dir string) Option {
+    return override(func(cfg *config.Config) {
+        cfg.DownloadDir = dir
+    })
+}
+
+// WithFilePermissions define a permissão do DOWNLOAD_DIR e do diretório de
+// cada download (FILE_PERMISSIONS)
+func WithFilePermissions(permissions os.FileMode) Option {
+    return override(func(cfg *config.Config) {
+        cfg.FilePermissions = permissions
+    })
+}
+
+// WithDownloader usa d no lugar do yt-dlp em todos os downloads; o reload
+// da configuração não o substitui
+func WithDownloader(d downloader.Downloader) Option {
+    return func(o *options) {
+        o.downloader = d
+    }
+}
+
+// WithStorage usa s no lugar do armazenamento padrão em DOWNLOAD_DIR
+// (utils.Storage) para localizar, listar e limpar os downloads
+func WithStorage(s handlers.Storage) Option {
+    return func(o *options) {
+        o.storage = s
+    }
+}
+
+// WithLogger envia os logs do servidor, inclusive os das requisições, para
+// logger no lugar do logger padrão do pacote log
+func WithLogger(logger *log.Logger) Option {
+    return func(o *options) {
+        o.logger = logger
+    }
+}
+
+// WithAuth liga a autenticação com as chaves "nome:token" e as cotas padrão
+// por chave (API_KEYS e API_KEY_*); adminKeys são os nomes que podem usar
+// as rotas de administração (ADMIN_KEYS)
+func WithAuth(keys []string, limits auth.Limits, adminKeys ...string) Option {
+    return override(func(cfg *config.Config) {
+        cfg.APIKeys = keys
+        cfg.APIKeyMaxJobs = limits.MaxConcurrentJobs
+        cfg.APIKeyMaxDaily = limits.MaxDailyDownloads
+        cfg.APIKeyMaxBytes = limits.MaxBytes
+        cfg.AdminKeys = adminKeys
+    })
+}
+
+// WithCleanupPolicy define o intervalo do cleanup automático e a porcentagem
+// dos downloads mais antigos removidos a cada execução (CLEANUP_INTERVAL e
+// CLEANUP_PERCENT)
+func WithCleanupPolicy(interval time.Duration, percent int) Option {
+    return override(func(cfg *config.Config) {
+        cfg.CleanupInterval = interval
+        cfg.CleanupPercent = percent
+    })
+}
+
+// WithRoutes troca os caminhos das rotas; os campos vazios não mudam
+func WithRoutes(routes Routes) Option {
+    return override(func(cfg *config.Config) {
+        set := func(dst *string, value string) {
+            if value != "" {
+                *dst = value
+            }
+        }
+        set(&cfg.DownloadHandler, routes.Download)
+        set(&cfg.PlaylistHandler, routes.Playlist)
+        set(&cfg.WebSocketHandler, routes.WebSocket)
+        set(&cfg.JobsHandler, routes.Jobs)
+        set(&cfg.EventsHandler, routes.Events)
+        set(&cfg.FetchHandler, routes.Fetch)
+        set(&cfg.AdminHandler, routes.Admin)
+    })
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Intervalo mínimo entre as limpezas de buckets cheios (clientes inativos)
+const sweepInterval = time.Minute
+
+// Limiter é um token bucket por cliente: cada requisição consome um token e
+// os tokens voltam a uma taxa fixa até o limite de burst
+type Limiter struct {
+	// Tokens devolvidos por segundo
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Result é a decisão de Allow com os dados para os headers X-RateLimit-*
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Tempo até haver um token disponível; zero se a requisição passou
+	RetryAfter time.Duration
+	// Tempo até o bucket encher de novo
+	Reset time.Duration
+}
+
+// New cria um limitador de perMinute requisições por minuto com rajadas de
+// até burst requisições (burst 0 usa perMinute). Com perMinute não positivo
+// o limitador fica desligado até SetLimit
+func New(perMinute, burst int) *Limiter {
+	l := &Limiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+	l.SetLimit(perMinute, burst)
+	return l
+}
+
+// SetLimit troca a taxa e o burst sem perder os buckets dos clientes, que
+// ficam limitados ao novo burst na próxima requisição
+func (l *Limiter) SetLimit(perMinute, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if perMinute <= 0 {
+		l.rate, l.burst = 0, 0
+		l.buckets = make(map[string]*bucket)
+		return
+	}
+	if burst <= 0 {
+		burst = perMinute
+	}
+	l.rate = float64(perMinute) / 60
+	l.burst = burst
+}
+
+// Enabled indica se o limitador está ligado; nil é um limitador desligado
+func (l *Limiter) Enabled() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate > 0
+}
+
+// Allow consome um token do cliente key, se houver
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return Result{Allowed: true}
+	}
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	result := Result{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = l.duration(float64(l.burst) - b.tokens)
+	return result
+}
+
+// duration é o tempo para devolver tokens ao bucket
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep remove os buckets que já estariam cheios, equivalentes a um cliente novo
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
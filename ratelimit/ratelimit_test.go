@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		requests  int
+		allowed   int
+	}{
+		{"desligado", 0, 0, 50, 50},
+		{"taxa negativa", -1, 5, 10, 10},
+		{"burst explícito", 60, 3, 5, 3},
+		{"burst padrão é a taxa", 4, 0, 6, 4},
+		{"burst de um", 1, 1, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.perMinute, tt.burst)
+			allowed := 0
+			for i := 0; i < tt.requests; i++ {
+				if l.Allow("cliente").Allowed {
+					allowed++
+				}
+			}
+			if allowed != tt.allowed {
+				t.Errorf("%d de %d requisições passaram, esperado %d", allowed, tt.requests, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestLimiterResult(t *testing.T) {
+	// Um token por segundo
+	l := New(60, 2)
+
+	first := l.Allow("a")
+	if !first.Allowed || first.Limit != 2 || first.Remaining != 1 || first.RetryAfter != 0 {
+		t.Fatalf("primeira requisição = %+v", first)
+	}
+	l.Allow("a")
+
+	denied := l.Allow("a")
+	if denied.Allowed || denied.Remaining != 0 {
+		t.Fatalf("terceira requisição = %+v, esperado bloqueio", denied)
+	}
+	if denied.RetryAfter <= 0 || denied.RetryAfter > time.Second {
+		t.Errorf("RetryAfter = %s, esperado até 1s", denied.RetryAfter)
+	}
+	if denied.Reset <= time.Second || denied.Reset > 2*time.Second {
+		t.Errorf("Reset = %s, esperado entre 1s e 2s", denied.Reset)
+	}
+
+	// Cada cliente tem o próprio bucket
+	if !l.Allow("b").Allowed {
+		t.Error("cliente b bloqueado pelo consumo de a")
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	// 600 por minuto: um token a cada 100ms
+	l := New(600, 1)
+	if !l.Allow("a").Allowed {
+		t.Fatal("primeira requisição bloqueada")
+	}
+	if l.Allow("a").Allowed {
+		t.Fatal("segunda requisição passou sem tokens")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !l.Allow("a").Allowed {
+		t.Fatal("token não foi devolvido depois de 150ms")
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	l := New(60, 5)
+	for i := 0; i < 5; i++ {
+		l.Allow("a")
+	}
+	if l.Allow("a").Allowed {
+		t.Fatal("burst de 5 permitiu a sexta requisição")
+	}
+
+	// Reduzir o burst não devolve tokens ao cliente
+	l.SetLimit(60, 2)
+	if l.Allow("a").Allowed {
+		t.Fatal("SetLimit devolveu tokens")
+	}
+
+	l.SetLimit(0, 0)
+	if l.Enabled() {
+		t.Fatal("limitador com taxa 0 continua ligado")
+	}
+	if !l.Allow("a").Allowed {
+		t.Fatal("limitador desligado bloqueou")
+	}
+
+	var nilLimiter *Limiter
+	if nilLimiter.Enabled() {
+		t.Fatal("limitador nil ligado")
+	}
+}
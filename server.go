@@ -0,0 +1,288 @@
+package ytapi
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/Arthur-Scaratti/yt-api/auth"
+    "github.com/Arthur-Scaratti/yt-api/config"
+    "github.com/Arthur-Scaratti/yt-api/events"
+    "github.com/Arthur-Scaratti/yt-api/handlers"
+    "github.com/Arthur-Scaratti/yt-api/ratelimit"
+    "github.com/Arthur-Scaratti/yt-api/utils"
+    "github.com/gin-gonic/gin"
+)
+
+// Tempo para as conexões HTTP terminarem depois que os jobs já encerraram
+const httpShutdownTimeout = 5 * time.Second
+
+// Server é uma instância da API com suas dependências: configuração,
+// downloader, armazenamento e hub de eventos. Nada é global, então várias
+// instâncias podem rodar no mesmo processo
+type Server struct {
+    settings *config.Holder
+    storage  handlers.Storage
+    hub      *events.Hub
+    api      *handlers.API
+    logger   *log.Logger
+
+    // Engine próprio, criado no primeiro Handler
+    routerOnce sync.Once
+    router     *gin.Engine
+
+    startOnce sync.Once
+}
+
+// New aplica as opções, valida a configuração e monta o servidor. Com cfg
+// nil a configuração base vem de config.Load() (ou de WithConfig). Nada é
+// executado até Start ou ListenAndServe
+func New(cfg *config.Config, opts ...Option) (*Server, error) {
+    o := &options{config: cfg}
+    for _, opt := range opts {
+        opt(o)
+    }
+    if o.config == nil {
+        o.config = config.Load()
+    }
+    if o.logger == nil {
+        o.logger = log.Default()
+    }
+    // As opções não alteram a configuração de quem chamou
+    base := *o.config
+    cfg = &base
+    for _, fn := range o.overrides {
+        cfg.Override(fn)
+    }
+    if err := cfg.Validate(); err != nil {
+        return nil, err
+    }
+
+    // Campos seguros podem ser recarregados com SIGHUP ou pela rota de admin
+    settings := config.NewHolder(cfg, o.logger)
+    var storage handlers.Storage = utils.NewStorage(settings, o.logger)
+    if o.storage != nil {
+        storage = o.storage
+    }
+    // Guarda até 2000 mensagens por job, por 1 hora após o fim do job
+    hub := events.NewHub(2000, time.Hour)
+
+    s := &Server{
+        settings: settings,
+        storage:  storage,
+        hub:      hub,
+        api:      handlers.New(settings, storage, hub, o.logger),
+        logger:   o.logger,
+    }
+    if o.downloader != nil {
+        s.api.SetDownloader(o.downloader)
+    }
+    return s, nil
+}
+
+// NewHandler monta um servidor e inicia a fila e o cleanup. O *Server é um
+// http.Handler, para servir com o próprio http.Server ou montar com
+// http.StripPrefix; chame Shutdown nele ao encerrar
+func NewHandler(opts ...Option) (*Server, error) {
+    s, err := New(nil, opts...)
+    if err != nil {
+        return nil, err
+    }
+    s.Start()
+    return s, nil
+}
+
+// RegisterRoutes monta um servidor e registra as rotas em group, que pode ter
+// prefixo e middlewares da aplicação. A fila e o cleanup já ficam rodando;
+// chame Shutdown no servidor retornado ao encerrar
+func RegisterRoutes(group *gin.RouterGroup, opts ...Option) (*Server, error) {
+    s, err := New(nil, opts...)
+    if err != nil {
+        return nil, err
+    }
+    s.RegisterRoutes(group)
+    s.Start()
+    return s, nil
+}
+
+// Config retorna a configuração em uso
+func (s *Server) Config() *config.Config {
+    return s.settings.Get()
+}
+
+// Router retorna o engine do Gin com as rotas da API
+func (s *Server) Router() *gin.Engine {
+    s.routerOnce.Do(func() {
+        gin.SetMode(s.settings.Get().GinMode)
+        s.router = gin.New()
+        // Sem proxies confiáveis o IP do cliente (rate limit, logs) é o da
+        // conexão; X-Forwarded-For de qualquer um seria um bucket novo
+        if err := s.router.SetTrustedProxies(s.settings.Get().TrustedProxies); err != nil {
+            s.logger.Printf("TRUSTED_PROXIES inválido, nenhum proxy é confiável: %v", err)
+            s.router.SetTrustedProxies(nil)
+        }
+        s.router.Use(gin.LoggerWithConfig(gin.LoggerConfig{
+            Formatter: AccessLogFormatter,
+            Output:    s.logger.Writer(),
+        }), gin.RecoveryWithWriter(s.logger.Writer()))
+        s.RegisterRoutes(&s.router.RouterGroup)
+    })
+    return s.router
+}
+
+// AccessLogFormatter é o formato padrão do log de acesso do Gin com o
+// ?token= mascarado, já que o token na query é aceito em todas as rotas.
+// Use-o no gin.LoggerConfig do engine ao embutir a API com RegisterRoutes
+func AccessLogFormatter(param gin.LogFormatterParams) string {
+    var statusColor, methodColor, resetColor string
+    if param.IsOutputColor() {
+        statusColor = param.StatusCodeColor()
+        methodColor = param.MethodColor()
+        resetColor = param.ResetColor()
+    }
+
+    if param.Latency > time.Minute {
+        param.Latency = param.Latency.Truncate(time.Second)
+    }
+    return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+        param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+        statusColor, param.StatusCode, resetColor,
+        param.Latency,
+        param.ClientIP,
+        methodColor, param.Method, resetColor,
+        auth.RedactToken(param.Path),
+        param.ErrorMessage,
+    )
+}
+
+// Handler retorna as rotas da API como http.Handler
+func (s *Server) Handler() http.Handler {
+    return s.Router()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    s.Router().ServeHTTP(w, r)
+}
+
+// API retorna os handlers, por exemplo para trocar o downloader
+func (s *Server) API() *handlers.API {
+    return s.api
+}
+
+// ReloadOnSignal recarrega a configuração a cada SIGHUP
+func (s *Server) ReloadOnSignal() {
+    s.settings.ReloadOnSignal()
+}
+
+// Start retoma os jobs pendentes e inicia o cleanup automático. Não bloqueia;
+// chamadas repetidas não têm efeito
+func (s *Server) Start() {
+    s.startOnce.Do(func() {
+        // Retoma os jobs interrompidos antes do cleanup para que não sejam removidos
+        s.api.ResumeJobs()
+        s.storage.StartAutoCleanup()
+        s.storage.RunSimpleCleanup()
+    })
+}
+
+// Shutdown para de aceitar downloads e o cleanup, espera os jobs em
+// andamento até o fim de ctx e fecha os WebSockets. Jobs interrompidos pelo
+// prazo continuam no journal e são retomados no próximo Start. As rotas
+// continuam respondendo; fechar o listener fica com quem serve o Handler
+func (s *Server) Shutdown(ctx context.Context) error {
+    s.storage.StopAutoCleanup()
+    return s.api.Shutdown(ctx)
+}
+
+// ListenAndServe inicia o servidor e atende em HOST:PORT até ctx ser
+// cancelado. Então encerra com Shutdown, esperando os downloads por até
+// SHUTDOWN_TIMEOUT, e fecha as conexões HTTP
+func (s *Server) ListenAndServe(ctx context.Context) error {
+    cfg := s.settings.Get()
+
+    // Cancelado depois dos jobs, encerra os streams SSE e as requisições
+    // que ainda esperam um download
+    requests, cancelRequests := context.WithCancel(context.Background())
+    defer cancelRequests()
+    srv := &http.Server{
+        Addr:        fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+        Handler:     s.Handler(),
+        BaseContext: func(net.Listener) context.Context { return requests },
+    }
+
+    s.Start()
+    serveErr := make(chan error, 1)
+    go func() {
+        serveErr <- srv.ListenAndServe()
+    }()
+    s.logger.Printf("Servidor ouvindo em %s", srv.Addr)
+
+    var err error
+    select {
+    case err = <-serveErr:
+        // Não chegou a atender (ex.: porta em uso)
+    case <-ctx.Done():
+        s.logger.Printf("Encerrando: aguardando downloads em andamento (até %s)", cfg.ShutdownTimeout)
+    }
+
+    jobsCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+    defer cancel()
+    if shutdownErr := s.Shutdown(jobsCtx); shutdownErr != nil {
+        s.logger.Printf("Downloads interrompidos, serão retomados na próxima inicialização: %v", shutdownErr)
+    }
+    if err != nil {
+        return err
+    }
+
+    cancelRequests()
+    httpCtx, cancelHTTP := context.WithTimeout(context.Background(), httpShutdownTimeout)
+    defer cancelHTTP()
+    if err := srv.Shutdown(httpCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+        return err
+    }
+    if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+    s.logger.Println("Servidor encerrado")
+    return nil
+}
+
+// RegisterRoutes registra as rotas da API em group. O prefixo do grupo entra
+// nas URLs devolvidas aos clientes (progressUrl, download...). Deve ser
+// chamado uma vez, antes de o servidor começar a atender
+func (s *Server) RegisterRoutes(group *gin.RouterGroup) {
+    cfg := s.settings.Get()
+    s.api.SetBasePath(strings.TrimSuffix(group.BasePath(), "/"))
+
+    // Chaves de API e cotas (desligado sem API_KEYS/API_KEYS_FILE)
+    r := group.Group("", s.api.AuthMiddleware())
+
+    // Rate limit por cliente; playlist/fetch e ws/events compartilham o bucket
+    downloadLimiter := ratelimit.New(cfg.RateLimitDownload, cfg.RateLimitDownloadBurst)
+    playlistLimiter := ratelimit.New(cfg.RateLimitPlaylist, cfg.RateLimitPlaylistBurst)
+    wsLimiter := ratelimit.New(cfg.RateLimitWS, cfg.RateLimitWSBurst)
+    s.settings.OnReload(func(cfg *config.Config) {
+        downloadLimiter.SetLimit(cfg.RateLimitDownload, cfg.RateLimitDownloadBurst)
+        playlistLimiter.SetLimit(cfg.RateLimitPlaylist, cfg.RateLimitPlaylistBurst)
+        wsLimiter.SetLimit(cfg.RateLimitWS, cfg.RateLimitWSBurst)
+    })
+    downloadLimit := s.api.RateLimitMiddleware(downloadLimiter)
+    playlistLimit := s.api.RateLimitMiddleware(playlistLimiter)
+    wsLimit := s.api.RateLimitMiddleware(wsLimiter)
+
+    // Rotas definidas na configuração
+    r.GET(cfg.DownloadHandler, downloadLimit, s.api.DownloadHandler)
+    r.GET(cfg.PlaylistHandler, playlistLimit, s.api.PlaylistHandler)
+    r.GET(cfg.WebSocketHandler, wsLimit, s.api.WebSocketHandler)
+    r.GET(cfg.EventsHandler, wsLimit, s.api.EventsHandler)
+    r.GET(cfg.FetchHandler, playlistLimit, s.api.FetchHandler)
+    r.GET(cfg.JobsHandler+"/:id", s.api.JobStatusHandler)
+    r.DELETE(cfg.JobsHandler+"/:id", s.api.CancelJobHandler)
+    r.POST(cfg.AdminHandler+"/reload", s.api.ReloadConfigHandler)
+}
@@ -1,34 +1,36 @@
 package ytapi
 
 import (
-    "fmt"
-    "github.com/Arthur-Scaratti/yt-api/config"
-    "github.com/Arthur-Scaratti/yt-api/handlers"
-    utils "github.com/Arthur-Scaratti/yt-api/utils"
+    "context"
+    "log"
+    "os"
+    "os/signal"
+    "syscall"
+
     "github.com/gin-gonic/gin"
 )
 
-func Start() *gin.Engine {
-    cfg := config.Load()
-    
-    if cfg.GinMode == "debug" {
-        gin.SetMode(gin.DebugMode)
-    } else {
-        gin.SetMode(gin.ReleaseMode)
+// Start carrega a configuração do ambiente, aplica as opções e atende até
+// receber SIGINT ou SIGTERM, encerrando os downloads graciosamente. Start só
+// retorna depois do encerramento: o engine retornado já não está servindo e
+// a fila já parou.
+//
+// Deprecated: Start bloqueia e o engine retornado não serve para nada. Use
+// New com Server.ListenAndServe para rodar o servidor, ou NewHandler ou
+// RegisterRoutes para embutir a API.
+func Start(opts ...Option) *gin.Engine {
+    s, err := New(nil, opts...)
+    // Não sobe com configuração inválida
+    if err != nil {
+        log.Fatal(err)
+    }
+    s.ReloadOnSignal()
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    if err := s.ListenAndServe(ctx); err != nil {
+        log.Fatal(err)
     }
     
-    r := gin.Default()
-    
-    // Configurar rotas usando variáveis de ambiente
-    r.GET(cfg.DownloadHandler, handlers.DownloadHandler)
-    r.GET(cfg.PlaylistHandler, handlers.PlaylistHandler)
-    r.GET(cfg.WebSocketHandler, handlers.WebSocketHandler)
-    
-    utils.StartAutoCleanup()
-    utils.RunSimpleCleanup()
-    // Usar host e porta das variáveis de ambiente
-    address := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-    r.Run(address)
-    
-    return r
-}
\ No newline at end of file
+    return s.Router()
+}
@@ -2,33 +2,113 @@ package utils
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Arthur-Scaratti/yt-api/config"
+	"github.com/Arthur-Scaratti/yt-api/jobid"
 )
 
-var cfg *config.Config
+// Marcador gravado no diretório do ID quando o download termina com sucesso
+const completeMarker = ".complete"
 
-func init() {
-    cfg = config.Load()
+// Storage são os downloads guardados em DOWNLOAD_DIR: diretórios por ID,
+// marcadores de conclusão, último acesso e cleanup
+type Storage struct {
+    // Configuração em uso; o cleanup lê a política a cada execução
+    settings *config.Holder
+    logger   *log.Logger
+
+    // Agendamento do cleanup automático; nil quando parado
+    cleanupMu       sync.Mutex
+    cleanupTicker   *time.Ticker
+    cleanupStop     chan struct{}
+    cleanupInterval time.Duration
+}
+
+// NewStorage cria o armazenamento; o cleanup registra o que faz em logger
+// (nil usa o logger padrão)
+func NewStorage(settings *config.Holder, logger *log.Logger) *Storage {
+    if logger == nil {
+        logger = log.Default()
+    }
+    s := &Storage{settings: settings, logger: logger}
+    // Registrado uma vez, e não a cada StartAutoCleanup, para não acumular
+    // listeners em ciclos de Stop/Start
+    settings.OnReload(s.rescheduleCleanup)
+    return s
+}
+
+// Dir é o DOWNLOAD_DIR
+func (s *Storage) Dir() string {
+    return s.settings.Get().DownloadDir
 }
-// Adicionar essas funções no arquivo download.go
 
-// Verifica se um ID já existe (pasta existe no diretório de downloads)
-func CheckExistingID(id string) bool {
-    dir := filepath.Join(cfg.DownloadDir, id)
-    if _, err := os.Stat(dir); os.IsNotExist(err) {
+// EnsureDir cria o DOWNLOAD_DIR se ainda não existir
+func (s *Storage) EnsureDir() error {
+    cfg := s.settings.Get()
+    if _, err := os.Stat(cfg.DownloadDir); os.IsNotExist(err) {
+        return os.MkdirAll(cfg.DownloadDir, cfg.FilePermissions)
+    }
+    return nil
+}
+
+// Diretório dos arquivos do ID dentro de DOWNLOAD_DIR
+func (s *Storage) JobDir(id jobid.ID) string {
+    return filepath.Join(s.Dir(), id.String())
+}
+
+// Verifica se um ID já existe e terminou (marcador de conclusão presente)
+func (s *Storage) CheckExistingID(id jobid.ID) bool {
+    if !s.IsComplete(id) {
         return false
     }
-	UpdateLastAccess(id)
+	s.UpdateLastAccess(id)
     return true
 }
 
+// Indica se o download do ID foi concluído
+func (s *Storage) IsComplete(id jobid.ID) bool {
+    _, err := os.Stat(filepath.Join(s.JobDir(id), completeMarker))
+    return err == nil
+}
+
+// Grava o marcador de conclusão do ID
+func (s *Storage) MarkComplete(id jobid.ID) error {
+    return os.WriteFile(filepath.Join(s.JobDir(id), completeMarker), nil, 0644)
+}
+
+// Indica se o arquivo é um download (ignora zip, controle e parciais)
+func IsMediaFile(name string) bool {
+    if name == "playlist.zip" || strings.HasPrefix(name, ".") {
+        return false
+    }
+    return !isPartialFile(name)
+}
+
+// PlaylistIndex extrai o índice do nome "N - Título" de um item de playlist.
+// O yt-dlp completa o índice com zeros ("03 - ...") conforme o tamanho da
+// playlist, então o número é comparado como inteiro
+func PlaylistIndex(name string) (int, bool) {
+    prefix, _, ok := strings.Cut(name, " - ")
+    if !ok {
+        return 0, false
+    }
+    index, err := strconv.Atoi(prefix)
+    if err != nil || index < 1 {
+        return 0, false
+    }
+    return index, true
+}
+
 // Retorna lista de arquivos organizados para playlist
-func GetPlaylistFiles(id string) ([]map[string]string, error) {
-    dir := filepath.Join(cfg.DownloadDir, id)
+func (s *Storage) GetPlaylistFiles(id jobid.ID) ([]map[string]string, error) {
+    dir := s.JobDir(id)
     files, err := os.ReadDir(dir)
     if err != nil {
         return nil, err
@@ -36,8 +116,8 @@ func GetPlaylistFiles(id string) ([]map[string]string, error) {
     
     var fileList []map[string]string
     for _, file := range files {
-        if file.Name() == "playlist.zip" {
-            continue // ignora o zip
+        if !IsMediaFile(file.Name()) {
+            continue // ignora o zip e arquivos de controle
         }
         
         // Extrai índice e título do nome do arquivo
@@ -65,16 +145,16 @@ func GetPlaylistFiles(id string) ([]map[string]string, error) {
 }
 
 // Retorna o primeiro arquivo encontrado para download único
-func GetSingleFile(id string) (string, error) {
-    dir := filepath.Join(cfg.DownloadDir, id)
+func (s *Storage) GetSingleFile(id jobid.ID) (string, error) {
+    dir := s.JobDir(id)
     files, err := os.ReadDir(dir)
     if err != nil || len(files) == 0 {
         return "", fmt.Errorf("nenhum arquivo encontrado")
     }
     
-    // Retorna o primeiro arquivo (ignora .zip se existir)
+    // Retorna o primeiro arquivo (ignora .zip e arquivos de controle)
     for _, file := range files {
-        if file.Name() != "playlist.zip" {
+        if IsMediaFile(file.Name()) {
             return filepath.Join(dir, file.Name()), nil
         }
     }
@@ -5,21 +5,26 @@ import (
     "os"
     "path/filepath"
     "sort"
+    "strings"
     "time"
+
+    "github.com/Arthur-Scaratti/yt-api/jobid"
+    "github.com/Arthur-Scaratti/yt-api/jobs"
 )
 
 type IDWithAccess struct {
-    ID           string
+    ID           jobid.ID
     LastAccessed time.Time
     DirPath      string
 }
 
-func RunSimpleCleanup() {
-    fmt.Println("🧹 Iniciando cleanup automático (50% dos mais antigos)...")
+func (s *Storage) RunSimpleCleanup() {
+    cfg := s.settings.Get()
+    s.logger.Printf("🧹 Iniciando cleanup automático (%d%% dos mais antigos)...", cfg.CleanupPercent)
 
     dirs, err := os.ReadDir(cfg.DownloadDir)
     if err != nil {
-        fmt.Printf("❌ Erro ao ler diretório: %v\n", err)
+        s.logger.Printf("❌ Erro ao ler diretório: %v", err)
         return
     }
     
@@ -30,20 +35,28 @@ func RunSimpleCleanup() {
         if !dir.IsDir() {
             continue
         }
+        // Só diretórios de download; ignora .jobs e qualquer outro
+        id, err := jobid.Parse(dir.Name())
+        if err != nil {
+            continue
+        }
         
-        id := dir.Name()
-        lastAccess := getLastAccess(id)
+        // Jobs na fila ou rodando ainda têm registro no journal
+        if s.isPendingJob(id) {
+            continue
+        }
+        lastAccess := s.getLastAccess(id)
         
         idsWithAccess = append(idsWithAccess, IDWithAccess{
             ID:           id,
             LastAccessed: lastAccess,
-            DirPath:      filepath.Join(cfg.DownloadDir, id),
+            DirPath:      s.JobDir(id),
         })
     }
     
     totalCount := len(idsWithAccess)
     if totalCount <= 1 {
-        fmt.Println("⚠️  Menos de 2 downloads, pulando cleanup")
+        s.logger.Println("⚠️  Menos de 2 downloads, pulando cleanup")
         return
     }
     
@@ -52,8 +65,8 @@ func RunSimpleCleanup() {
         return idsWithAccess[i].LastAccessed.Before(idsWithAccess[j].LastAccessed)
     })
     
-    // Calcula quantos remover (50%)
-    toRemoveCount := totalCount / 2
+    // Calcula quantos remover (CLEANUP_PERCENT)
+    toRemoveCount := totalCount * cfg.CleanupPercent / 100
     if toRemoveCount == 0 {
         toRemoveCount = 1 // Remove pelo menos 1 se tiver mais de 1
     }
@@ -64,16 +77,16 @@ func RunSimpleCleanup() {
         idToRemove := idsWithAccess[i]
         
         if err := os.RemoveAll(idToRemove.DirPath); err == nil {
-            fmt.Printf("🗑️  Removido: %s (último acesso: %s)\n", 
+            s.logger.Printf("🗑️  Removido: %s (último acesso: %s)", 
                 idToRemove.ID, 
                 formatTimeAgo(idToRemove.LastAccessed))
             removedCount++
         } else {
-            fmt.Printf("❌ Erro ao remover %s: %v\n", idToRemove.ID, err)
+            s.logger.Printf("❌ Erro ao remover %s: %v", idToRemove.ID, err)
         }
     }
     
-    fmt.Printf("✅ Cleanup concluído: %d/%d downloads removidos\n", removedCount, totalCount)
+    s.logger.Printf("✅ Cleanup concluído: %d/%d downloads removidos", removedCount, totalCount)
 }
 
 // Formata tempo para exibição amigável
@@ -93,4 +106,32 @@ func formatTimeAgo(t time.Time) string {
         days := hours / 24
         return fmt.Sprintf("%dd atrás", days)
     }
+}
+
+// Remove os arquivos parciais (.part, .ytdl, fragmentos) deixados pelo yt-dlp
+func (s *Storage) RemovePartialFiles(id jobid.ID) error {
+    dir := s.JobDir(id)
+    files, err := os.ReadDir(dir)
+    if err != nil {
+        return err
+    }
+
+    for _, file := range files {
+        name := file.Name()
+        if isPartialFile(name) {
+            if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func (s *Storage) isPendingJob(id jobid.ID) bool {
+    _, err := os.Stat(filepath.Join(s.Dir(), jobs.JournalDirName, id.String()+".json"))
+    return err == nil
+}
+
+func isPartialFile(name string) bool {
+    return strings.HasSuffix(name, ".part") || strings.Contains(name, ".part-Frag") || strings.HasSuffix(name, ".ytdl")
 }
\ No newline at end of file
@@ -1,18 +1,58 @@
 package utils
 
 import (
-    "fmt"
     "time"
+
+    "github.com/Arthur-Scaratti/yt-api/config"
 )
 
-func StartAutoCleanup() {
-    fmt.Println("🚀 Iniciando sistema de cleanup automático (12h)")
-    ticker := time.NewTicker(500 * time.Minute)
+func (s *Storage) StartAutoCleanup() {
+    s.cleanupMu.Lock()
+    defer s.cleanupMu.Unlock()
+    if s.cleanupTicker != nil {
+        return
+    }
+
+    interval := s.settings.Get().CleanupInterval
+    s.logger.Printf("🚀 Iniciando sistema de cleanup automático (%s)", interval)
+    ticker := time.NewTicker(interval)
+    stop := make(chan struct{})
+    s.cleanupTicker, s.cleanupStop, s.cleanupInterval = ticker, stop, interval
     go func() {
-        for range ticker.C {
-            RunSimpleCleanup()
+        for {
+            select {
+            case <-ticker.C:
+                s.RunSimpleCleanup()
+            case <-stop:
+                return
+            }
         }
     }()
     
-    fmt.Println("⏰ Cleanup agendado para rodar a cada 12 horas")
-}
\ No newline at end of file
+    s.logger.Printf("⏰ Cleanup agendado para rodar a cada %s", interval)
+}
+
+// rescheduleCleanup aplica o intervalo do reload ao agendamento em andamento
+func (s *Storage) rescheduleCleanup(cfg *config.Config) {
+    s.cleanupMu.Lock()
+    defer s.cleanupMu.Unlock()
+    if s.cleanupTicker == nil || cfg.CleanupInterval == s.cleanupInterval {
+        return
+    }
+    s.cleanupInterval = cfg.CleanupInterval
+    s.cleanupTicker.Reset(s.cleanupInterval)
+    s.logger.Printf("⏰ Cleanup reagendado para rodar a cada %s", s.cleanupInterval)
+}
+
+// StopAutoCleanup para o agendamento; um cleanup em andamento termina normalmente
+func (s *Storage) StopAutoCleanup() {
+    s.cleanupMu.Lock()
+    defer s.cleanupMu.Unlock()
+    if s.cleanupTicker == nil {
+        return
+    }
+    s.cleanupTicker.Stop()
+    close(s.cleanupStop)
+    s.cleanupTicker, s.cleanupStop = nil, nil
+    s.logger.Println("⏹️  Cleanup automático parado")
+}
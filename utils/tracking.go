@@ -5,14 +5,16 @@ import (
     "os"
     "path/filepath"
     "time"
+
+    "github.com/Arthur-Scaratti/yt-api/jobid"
 )
 
 type AccessInfo struct {
     LastAccessed time.Time `json:"last_accessed"`
 }
 
-func UpdateLastAccess(id string) {
-    accessPath := filepath.Join(cfg.DownloadDir, id, ".access")
+func (s *Storage) UpdateLastAccess(id jobid.ID) {
+    accessPath := filepath.Join(s.JobDir(id), ".access")
     access := AccessInfo{
         LastAccessed: time.Now(),
     }
@@ -21,8 +23,8 @@ func UpdateLastAccess(id string) {
     os.WriteFile(accessPath, data, 0644)
 }
 
-func getLastAccess(id string) time.Time {
-    accessPath := filepath.Join(cfg.DownloadDir, id, ".access")
+func (s *Storage) getLastAccess(id jobid.ID) time.Time {
+    accessPath := filepath.Join(s.JobDir(id), ".access")
     data, err := os.ReadFile(accessPath)
     if err != nil {
         return time.Time{}
@@ -0,0 +1,151 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxItems é o limite de itens de index quando a configuração não define
+const DefaultMaxItems = 100
+
+// Formats são os valores aceitos em format, já normalizados; best baixa o
+// melhor arquivo único sem conversão
+var Formats = []string{"mp3", "mp4", "mkv", "webm", "best"}
+
+// Qualities são os valores aceitos em quality, já normalizados
+var Qualities = []string{"144p", "240p", "360p", "480p", "720p", "1080p", "1440p", "2160p"}
+
+var (
+	ErrInvalidFormat  = errors.New("format inválido")
+	ErrInvalidQuality = errors.New("quality inválida")
+	ErrInvalidIndex   = errors.New("index inválido")
+	ErrTooManyItems   = errors.New("index seleciona itens demais")
+)
+
+// Item de index: "3", "-2" (a partir do fim), "3-7" ou "-5--1"
+var indexItem = regexp.MustCompile(`^(-?\d+)(?:-(-?\d+))?$`)
+
+// Format normaliza o formato ("MP4" vira "mp4") e recusa valores fora de
+// Formats
+func Format(format string) (string, error) {
+	f := strings.ToLower(strings.TrimSpace(format))
+	for _, allowed := range Formats {
+		if f == allowed {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q (valores aceitos: %s)", ErrInvalidFormat, format, strings.Join(Formats, ", "))
+}
+
+// UsesQuality indica se a quality muda o download no formato já normalizado:
+// só os formatos de vídeo limitam a altura (ver downloader.BuildFormatSelector)
+func UsesQuality(format string) bool {
+	switch format {
+	case "mp4", "mkv", "webm":
+		return true
+	}
+	return false
+}
+
+// Quality normaliza a qualidade ("720", "720P" viram "720p") e recusa
+// valores fora de Qualities
+func Quality(quality string) (string, error) {
+	q := strings.ToLower(strings.TrimSpace(quality))
+	if !strings.HasSuffix(q, "p") {
+		q += "p"
+	}
+	for _, allowed := range Qualities {
+		if q == allowed {
+			return q, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q (valores aceitos: %s)", ErrInvalidQuality, quality, strings.Join(Qualities, ", "))
+}
+
+// Index valida a seleção de itens de playlist (--playlist-items): índices
+// simples, faixas e listas separadas por vírgula, com índices negativos
+// contando do fim. Retorna a seleção normalizada (ordenada, sem repetições,
+// faixas unidas), para que seleções equivalentes gerem o mesmo id.
+// maxItems limita a quantidade de itens; 0 usa DefaultMaxItems
+func Index(index string, maxItems int) (string, error) {
+	if maxItems <= 0 {
+		maxItems = DefaultMaxItems
+	}
+
+	// Positivos e negativos são unidos separadamente: sem saber o tamanho da
+	// playlist não há como compará-los
+	var positive, negative []span
+	for _, item := range strings.Split(index, ",") {
+		item = strings.TrimSpace(item)
+		match := indexItem.FindStringSubmatch(item)
+		if match == nil {
+			return "", fmt.Errorf("%w: %q", ErrInvalidIndex, item)
+		}
+
+		start, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || start == 0 {
+			return "", fmt.Errorf("%w: %q", ErrInvalidIndex, item)
+		}
+		end := start
+		if match[2] != "" {
+			end, err = strconv.ParseInt(match[2], 10, 64)
+			if err != nil || end == 0 || (start < 0) != (end < 0) || end < start {
+				return "", fmt.Errorf("%w: %q", ErrInvalidIndex, item)
+			}
+		}
+
+		if start > 0 {
+			positive = append(positive, span{start, end})
+		} else {
+			negative = append(negative, span{start, end})
+		}
+	}
+
+	spans := append(merge(positive), merge(negative)...)
+	var count int64
+	parts := make([]string, 0, len(spans))
+	for _, s := range spans {
+		// Compara antes de somar para não estourar com faixas enormes
+		size := s.end - s.start + 1
+		if size <= 0 || size > int64(maxItems)-count {
+			return "", fmt.Errorf("%w (máximo %d)", ErrTooManyItems, maxItems)
+		}
+		count += size
+		parts = append(parts, s.String())
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// span é uma faixa inclusiva de índices com o mesmo sinal
+type span struct {
+	start, end int64
+}
+
+func (s span) String() string {
+	if s.start == s.end {
+		return strconv.FormatInt(s.start, 10)
+	}
+	return fmt.Sprintf("%d-%d", s.start, s.end)
+}
+
+// merge ordena as faixas e une as que se sobrepõem ou são vizinhas
+func merge(spans []span) []span {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var merged []span
+	for _, s := range spans {
+		last := len(merged) - 1
+		if last >= 0 && s.start <= merged[last].end+1 {
+			merged[last].end = max(merged[last].end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
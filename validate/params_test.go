@@ -0,0 +1,116 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		index    string
+		maxItems int
+		want     string
+		err      error
+	}{
+		{"único", "3", 0, "3", nil},
+		{"espaços", " 3 , 5 ", 0, "3,5", nil},
+		{"ordena e remove repetidos", "5,3,5", 0, "3,5", nil},
+		{"une faixas vizinhas", "1-3,4,6-7", 0, "1-4,6-7", nil},
+		{"une faixas sobrepostas", "1-5,3-8", 0, "1-8", nil},
+		{"negativos", "-3--1,-5", 0, "-5,-3--1", nil},
+		{"positivos antes dos negativos", "-1,2", 0, "2,-1", nil},
+		{"zero", "0", 0, "", ErrInvalidIndex},
+		{"faixa invertida", "7-3", 0, "", ErrInvalidIndex},
+		{"sinais misturados", "-2-3", 0, "", ErrInvalidIndex},
+		{"texto", "abc", 0, "", ErrInvalidIndex},
+		{"vazio", "", 0, "", ErrInvalidIndex},
+		{"item vazio", "1,,2", 0, "", ErrInvalidIndex},
+		{"opção do yt-dlp", "--exec", 0, "", ErrInvalidIndex},
+		{"no limite", "1-10", 10, "1-10", nil},
+		{"acima do limite", "1-11", 10, "", ErrTooManyItems},
+		{"limite somando faixas", "1-6,-5--1", 10, "", ErrTooManyItems},
+		{"limite padrão", "1-101", 0, "", ErrTooManyItems},
+		{"faixa enorme", "1-9223372036854775807", 0, "", ErrTooManyItems},
+		{"fora de int64", "99999999999999999999", 0, "", ErrInvalidIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Index(tt.index, tt.maxItems)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Index(%q) erro = %v, esperado %v", tt.index, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Index(%q) erro inesperado: %v", tt.index, err)
+			}
+			if got != tt.want {
+				t.Errorf("Index(%q) = %q, esperado %q", tt.index, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		quality string
+		want    string
+		err     error
+	}{
+		{"720p", "720p", nil},
+		{"720", "720p", nil},
+		{"1080P", "1080p", nil},
+		{" 480p ", "480p", nil},
+		{"2160", "2160p", nil},
+		{"721p", "", ErrInvalidQuality},
+		{"best", "", ErrInvalidQuality},
+		{"", "", ErrInvalidQuality},
+		{"p", "", ErrInvalidQuality},
+	}
+	for _, tt := range tests {
+		got, err := Quality(tt.quality)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Quality(%q) erro = %v, esperado %v", tt.quality, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Quality(%q) = %q, esperado %q", tt.quality, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+		err    error
+	}{
+		{"mp4", "mp4", nil},
+		{"MP3", "mp3", nil},
+		{" webm ", "webm", nil},
+		{"Best", "best", nil},
+		{"flac", "", ErrInvalidFormat},
+		{"", "", ErrInvalidFormat},
+	}
+	for _, tt := range tests {
+		got, err := Format(tt.format)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Format(%q) erro = %v, esperado %v", tt.format, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Format(%q) = %q, esperado %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestUsesQuality(t *testing.T) {
+	for _, format := range Formats {
+		want := format == "mp4" || format == "mkv" || format == "webm"
+		if got := UsesQuality(format); got != want {
+			t.Errorf("UsesQuality(%q) = %v, esperado %v", format, got, want)
+		}
+	}
+}
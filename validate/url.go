@@ -0,0 +1,163 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidURL     = errors.New("url inválida")
+	ErrOptionLikeURL  = errors.New("url não pode começar com -")
+	ErrSchemeDenied   = errors.New("esquema da url não permitido")
+	ErrHostDenied     = errors.New("host não permitido")
+	ErrPrivateAddress = errors.New("url aponta para endereço interno")
+)
+
+// Esquemas aceitos quando a configuração não define nenhum
+var defaultSchemes = []string{"http", "https"}
+
+// Faixas que não são privadas para net.IP mas também não devem ser acessadas
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8",     // "esta rede"
+	"100.64.0.0/10", // CGNAT
+	"192.0.0.0/24",  // atribuições de protocolo do IETF
+	"198.18.0.0/15", // testes de benchmark
+	"240.0.0.0/4",   // reservado
+)
+
+// URLPolicy decide quais URLs podem ser entregues ao yt-dlp
+type URLPolicy struct {
+	// Esquemas aceitos; vazio aceita http e https
+	Schemes []string
+	// Hosts aceitos; vazio aceita qualquer host. "youtube.com" aceita o
+	// domínio e seus subdomínios, "*.youtube.com" apenas os subdomínios
+	AllowHosts []string
+	// Hosts recusados, no mesmo formato; têm precedência sobre AllowHosts
+	DenyHosts []string
+	// Não resolve o host nem bloqueia endereços internos (loopback, redes
+	// privadas, link-local como 169.254.169.254)
+	AllowPrivate bool
+
+	// Resolver usado para checar os endereços do host; nil usa o padrão
+	Resolver *net.Resolver
+}
+
+// Check valida a URL: esquema, hosts permitidos e, depois de resolver o
+// DNS, se algum endereço do host é interno
+func (p *URLPolicy) Check(ctx context.Context, raw string) error {
+	raw = strings.TrimSpace(raw)
+	// O yt-dlp interpretaria como opção de linha de comando
+	if strings.HasPrefix(raw, "-") {
+		return ErrOptionLikeURL
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	if !p.schemeAllowed(strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: %s", ErrSchemeDenied, u.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return ErrInvalidURL
+	}
+	if matchAny(p.DenyHosts, host) || (len(p.AllowHosts) > 0 && !matchAny(p.AllowHosts, host)) {
+		return fmt.Errorf("%w: %s", ErrHostDenied, host)
+	}
+
+	if p.AllowPrivate {
+		return nil
+	}
+	return p.checkAddresses(ctx, host)
+}
+
+func (p *URLPolicy) schemeAllowed(scheme string) bool {
+	schemes := p.Schemes
+	if len(schemes) == 0 {
+		schemes = defaultSchemes
+	}
+	for _, allowed := range schemes {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddresses recusa o host se qualquer um dos endereços resolvidos for
+// interno, para que um DNS com vários registros não escape do bloqueio
+func (p *URLPolicy) checkAddresses(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if isInternal(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: host %s não resolvido", ErrInvalidURL, host)
+	}
+	for _, addr := range addrs {
+		if isInternal(addr.IP) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// isInternal indica se o endereço é loopback, privado, link-local,
+// multicast ou reservado
+func isInternal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny compara o host com os padrões: "exemplo.com" casa com o
+// domínio e subdomínios, "*.exemplo.com" só com subdomínios
+func matchAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern || strings.HasSuffix(host, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestURLPolicyCheck(t *testing.T) {
+	youtube := URLPolicy{AllowHosts: []string{"youtube.com", "youtu.be"}, AllowPrivate: true}
+	subdomains := URLPolicy{AllowHosts: []string{"*.example.com"}, AllowPrivate: true}
+	denied := URLPolicy{DenyHosts: []string{"bad.example.com"}, AllowHosts: []string{"example.com"}, AllowPrivate: true}
+	ftp := URLPolicy{Schemes: []string{"ftp"}, AllowPrivate: true}
+	private := URLPolicy{}
+
+	tests := []struct {
+		name   string
+		policy URLPolicy
+		url    string
+		err    error
+	}{
+		{"host permitido", youtube, "https://youtube.com/watch?v=x", nil},
+		{"subdomínio", youtube, "https://www.youtube.com/watch?v=x", nil},
+		{"host em maiúsculas com ponto final", youtube, "https://WWW.YouTube.com./watch", nil},
+		{"host fora da lista", youtube, "https://vimeo.com/1", ErrHostDenied},
+		{"sufixo sem ponto", youtube, "https://notyoutube.com/", ErrHostDenied},
+		{"curinga aceita subdomínio", subdomains, "https://a.example.com/", nil},
+		{"curinga recusa o domínio", subdomains, "https://example.com/", ErrHostDenied},
+		{"negado tem precedência", denied, "https://bad.example.com/", ErrHostDenied},
+		{"opção do yt-dlp", youtube, "--exec=id", ErrOptionLikeURL},
+		{"opção com espaços", youtube, "  -o x", ErrOptionLikeURL},
+		{"esquema file", youtube, "file:///etc/passwd", ErrSchemeDenied},
+		{"esquema fora da lista", ftp, "https://example.com/", ErrSchemeDenied},
+		{"esquema configurado", ftp, "FTP://example.com/", nil},
+		{"sem host", youtube, "https:///watch", ErrInvalidURL},
+		{"url inválida", youtube, "https://[::1", ErrInvalidURL},
+		{"loopback", private, "http://127.0.0.1/", ErrPrivateAddress},
+		{"rede privada", private, "http://10.1.2.3:8080/", ErrPrivateAddress},
+		{"metadados da nuvem", private, "http://169.254.169.254/latest", ErrPrivateAddress},
+		{"cgnat", private, "http://100.64.0.1/", ErrPrivateAddress},
+		{"ipv6 loopback", private, "http://[::1]/", ErrPrivateAddress},
+		{"ipv6 mapeado", private, "http://[::ffff:192.168.0.1]/", ErrPrivateAddress},
+		{"não especificado", private, "http://0.0.0.0/", ErrPrivateAddress},
+		{"ip público", private, "http://93.184.216.34/", nil},
+		{"allowPrivate libera", URLPolicy{AllowPrivate: true}, "http://127.0.0.1/", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(context.Background(), tt.url)
+			if tt.err == nil && err != nil {
+				t.Fatalf("Check(%q) erro inesperado: %v", tt.url, err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Check(%q) erro = %v, esperado %v", tt.url, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestURLPolicyCheckResolvesHost(t *testing.T) {
+	// localhost vem do /etc/hosts, sem depender de DNS externo
+	err := (&URLPolicy{}).Check(context.Background(), "http://localhost:8080/")
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("Check(localhost) erro = %v, esperado %v", err, ErrPrivateAddress)
+	}
+}